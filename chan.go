@@ -0,0 +1,122 @@
+package progressbar
+
+import "sync/atomic"
+
+// Count receives values from ch until it's closed, adding one to bar
+// for each of them, and then finishes the bar. It returns the first error
+// of the bar, if any, once ch is closed.
+func Count[T any](bar *ProgressBar, ch <-chan T) error {
+	var err error
+	for range ch {
+		if e := bar.Add(1); err == nil {
+			err = e
+		}
+	}
+	if e := bar.Finish(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// Sum is like Count, but adds the values received from ch to bar.
+func Sum[D ~int | ~int8 | ~int16 | ~int32 | ~int64](bar *ProgressBar, ch <-chan D) error {
+	var err error
+	for delta := range ch {
+		if e := bar.Add64(int64(delta)); err == nil {
+			err = e
+		}
+	}
+	if e := bar.Finish(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// Tracker is like sync.WaitGroup advancing a progress bar as tasks are done.
+//
+// The bar's maximum is the number of tasks, which Add raises, unless the bar
+// is a spinner of unknown length. It's only advanced from a single goroutine,
+// so that the tasks calling Done don't contend for its lock. The bar
+// is finished once all the tasks are done, or, if there are none,
+// once Wait is called.
+type Tracker struct {
+	bar     *ProgressBar
+	spinner bool // so the bar's maximum isn't the number of tasks
+	total   atomic.Int64
+	done    atomic.Int64 // tasks done, but not added to the bar yet
+	wait    atomic.Bool  // set once Wait is called
+
+	kick     chan struct{}
+	finished chan struct{}
+	err      error // of the bar, once finished
+}
+
+// NewTracker creates a Tracker of as many tasks as bar's maximum,
+// or none if bar is a spinner.
+func NewTracker(bar *ProgressBar) *Tracker {
+	max := bar.State().Max
+	t := &Tracker{
+		bar:      bar,
+		spinner:  max < 0,
+		kick:     make(chan struct{}, 1),
+		finished: make(chan struct{}),
+	}
+	if max > 0 {
+		t.total.Store(max)
+	}
+	go t.run()
+	return t
+}
+
+// Add adds n tasks to the ones tracked. Like with sync.WaitGroup,
+// it must not be called once all the tasks tracked are done.
+func (t *Tracker) Add(n int) {
+	t.total.Add(int64(n))
+	if !t.spinner {
+		_ = t.bar.AddMax(n)
+	}
+	t.signal()
+}
+
+// Done marks a task as done.
+func (t *Tracker) Done() {
+	t.done.Add(1)
+	t.signal()
+}
+
+// Wait waits until all the tasks are done, and returns
+// the first error of the bar, if any.
+func (t *Tracker) Wait() error {
+	t.wait.Store(true)
+	t.signal()
+	<-t.finished
+	return t.err
+}
+
+// signal wakes the goroutine advancing the bar up, unless it's already awake.
+func (t *Tracker) signal() {
+	select {
+	case t.kick <- struct{}{}:
+	default:
+	}
+}
+
+// run advances the bar as tasks are done, and finishes it once they all are.
+// While there are no tasks at all, they may be yet to be added, so it waits
+// for Wait to be called before finishing the bar.
+func (t *Tracker) run() {
+	var count int64
+	for total := t.total.Load(); count < total || total == 0 && !t.wait.Load(); total = t.total.Load() {
+		<-t.kick
+		if n := t.done.Swap(0); n > 0 {
+			count += n
+			if err := t.bar.Add64(n); t.err == nil {
+				t.err = err
+			}
+		}
+	}
+	if err := t.bar.Finish(); t.err == nil {
+		t.err = err
+	}
+	close(t.finished)
+}
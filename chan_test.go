@@ -0,0 +1,87 @@
+package progressbar
+
+import (
+	"io"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCount(t *testing.T) {
+	bar := New(10, OptionWriter(io.Discard))
+	ch := make(chan string)
+	go func() {
+		for range 7 {
+			ch <- "done"
+		}
+		close(ch)
+	}()
+	assert.NoError(t, Count(bar, ch))
+	s := bar.State()
+	assert.Equal(t, 10.0, s.CurrentBytes)
+	assert.True(t, s.Finished)
+}
+
+func TestSum(t *testing.T) {
+	bar := New(10, OptionWriter(io.Discard))
+	ch := make(chan int, 3)
+	ch <- 4
+	ch <- 8
+	ch <- 1
+	close(ch)
+	assert.ErrorIs(t, Sum(bar, ch), ErrExceedsMax)
+	assert.True(t, bar.State().Finished)
+}
+
+func TestTracker(t *testing.T) {
+	bar := New(50, OptionWriter(io.Discard))
+	tracker := NewTracker(bar)
+	tracker.Add(50)
+	var wg sync.WaitGroup
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tracker.Done()
+		}()
+	}
+	assert.NoError(t, tracker.Wait())
+	wg.Wait()
+	s := bar.State()
+	assert.Equal(t, int64(100), s.Max)
+	assert.Equal(t, int64(100), s.Current)
+	assert.True(t, s.Finished)
+
+	tracker = NewTracker(New(0, OptionWriter(io.Discard)))
+	assert.NoError(t, tracker.Wait())
+}
+
+func TestTrackerAdd(t *testing.T) {
+	bar := New(0, OptionWriter(io.Discard))
+	tracker := NewTracker(bar)
+	runtime.Gosched() // let the tracker start before any tasks are added
+	tracker.Add(5)
+	for range 5 {
+		go tracker.Done()
+	}
+	assert.NoError(t, tracker.Wait())
+	s := bar.State()
+	assert.Equal(t, int64(5), s.Max)
+	assert.Equal(t, int64(5), s.Current)
+	assert.True(t, s.Finished)
+}
+
+func TestTrackerSpinner(t *testing.T) {
+	bar := New(-1, OptionWriter(io.Discard))
+	tracker := NewTracker(bar)
+	tracker.Add(2)
+	tracker.Done()
+	tracker.Done()
+	assert.NoError(t, tracker.Wait())
+	s := bar.State()
+	assert.Equal(t, int64(-1), s.Max)
+	assert.Equal(t, int64(2), s.Current)
+	assert.True(t, s.Finished)
+}
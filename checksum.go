@@ -0,0 +1,90 @@
+package progressbar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+	"slices"
+)
+
+// ErrChecksumMismatch is returned by Reader's Close if the data read
+// doesn't have the checksum expected.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ErrNotFullyRead is returned by Reader's Close if there's a checksum to verify,
+// but the data has not been read to the end.
+var ErrNotFullyRead = errors.New("data not fully read")
+
+// ReaderOption is the type all Reader options need to adhere to.
+type ReaderOption func(r *Reader)
+
+// ReaderHash makes Reader write the data read to given hashes,
+// whose sums are returned by Sums once all of it is read.
+//
+// The data is considered all read at EOF, or once progress bar
+// of known length is full, as the content may be read exactly
+// without reaching EOF, e.g. with io.ReadFull.
+func ReaderHash(hashes ...hash.Hash) ReaderOption {
+	return func(r *Reader) {
+		for _, h := range hashes {
+			r.hashes = append(r.hashes, h)
+			r.checksums = append(r.checksums, nil)
+		}
+	}
+}
+
+// ReaderChecksum makes Reader write the data read to given hash, and check
+// that its sum is as expected on Close, where progress bar is described
+// as "verifying" while it's checked. The data must be all read for that,
+// as with ReaderHash, otherwise Close fails without reading the rest of it.
+func ReaderChecksum(h hash.Hash, sum []byte) ReaderOption {
+	return func(r *Reader) {
+		r.hashes = append(r.hashes, h)
+		r.checksums = append(r.checksums, slices.Clone(sum))
+	}
+}
+
+// Sums returns the sums of the hashes of Reader in the order the options
+// are given, or nil if not all the data is read yet.
+func (r *Reader) Sums() [][]byte {
+	if !r.allRead() {
+		return nil
+	}
+	sums := make([][]byte, len(r.hashes))
+	for i, h := range r.hashes {
+		sums[i] = h.Sum(nil)
+	}
+	return sums
+}
+
+// allRead reports whether all the data is read, i.e. Reader has reached EOF,
+// or progress bar of known length is full.
+func (r *Reader) allRead() bool {
+	if r.eof {
+		return true
+	}
+	s := r.bar.State()
+	return s.Max >= 0 && s.Current >= s.Max
+}
+
+// verify checks that the sums are as expected, if there are checksums
+// to verify, which requires all the data to be read. The bar is described
+// as "verifying" meanwhile, and its description is restored if they are.
+func (r *Reader) verify() error {
+	if !slices.ContainsFunc(r.checksums, func(sum []byte) bool { return sum != nil }) {
+		return nil
+	}
+	if !r.allRead() {
+		return ErrNotFullyRead
+	}
+	description := r.bar.State().Description
+	r.bar.SetDescription("verifying")
+	for i, sum := range r.Sums() {
+		if expected := r.checksums[i]; expected != nil && !bytes.Equal(sum, expected) {
+			return fmt.Errorf("%w: %x, expected %x", ErrChecksumMismatch, sum, expected)
+		}
+	}
+	r.bar.SetDescription(description)
+	return nil
+}
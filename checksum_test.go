@@ -0,0 +1,59 @@
+package progressbar
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReaderHash(t *testing.T) {
+	bar := New(7, OptionWriter(io.Discard))
+	r := NewReader(strings.NewReader("content"), bar, ReaderHash(sha256.New(), md5.New()))
+	b := make([]byte, 3)
+	r.Read(b)
+	assert.Nil(t, r.Sums())
+	io.Copy(io.Discard, &r)
+
+	sha, md := sha256.Sum256([]byte("content")), md5.Sum([]byte("content"))
+	assert.Equal(t, [][]byte{sha[:], md[:]}, r.Sums())
+	assert.NoError(t, r.Close())
+}
+
+func TestReaderChecksum(t *testing.T) {
+	sum := sha256.Sum256([]byte("content"))
+	buf := strings.Builder{}
+	bar := New(7, OptionWidth(10), OptionDescription("content"), OptionWriter(&buf))
+	r := NewReader(strings.NewReader("content"), bar, ReaderChecksum(sha256.New(), sum[:]))
+	io.Copy(io.Discard, &r)
+	assert.NoError(t, r.Close())
+	assert.Contains(t, buf.String(), "verifying 100% |██████████| ")
+	assert.Equal(t, "content 100% |██████████| ", bar.String())
+	assert.Len(t, r.Sums(), 1)
+
+	bar = New(7, OptionWidth(10), OptionWriter(io.Discard))
+	r = NewReader(strings.NewReader("corrupt"), bar, ReaderChecksum(sha256.New(), sum[:]))
+	io.Copy(io.Discard, &r)
+	err := r.Close()
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.Equal(t, "verifying 100% |██████████| failed ", bar.String())
+	assert.True(t, bar.State().Failed)
+
+	bar = New(7, OptionWidth(10), OptionDescription("content"), OptionWriter(io.Discard))
+	src := strings.NewReader("content")
+	r = NewReader(src, bar, ReaderChecksum(sha256.New(), sum[:]))
+	r.Read(make([]byte, 3))
+	assert.ErrorIs(t, r.Close(), ErrNotFullyRead)
+	assert.Equal(t, 4, src.Len()) // the rest isn't read
+	assert.Equal(t, "content  42% |████      | failed ", bar.String())
+	assert.Nil(t, r.Sums())
+
+	bar = New(7, OptionWidth(10), OptionWriter(io.Discard))
+	r = NewReader(strings.NewReader("content"), bar, ReaderChecksum(sha256.New(), sum[:]))
+	io.ReadFull(&r, make([]byte, 7)) // exactly, without reaching EOF
+	assert.Len(t, r.Sums(), 1)
+	assert.NoError(t, r.Close())
+}
@@ -0,0 +1,53 @@
+package progressbar
+
+import "time"
+
+// Clock is the source of time of progress bars, which can be replaced
+// with OptionClock, e.g. to test the output deterministically.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTicker returns a new Ticker ticking with period d.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks of Clock at intervals, like time.Ticker.
+type Ticker interface {
+	// C returns the channel on which the ticks are delivered.
+	C() <-chan time.Time
+
+	// Stop turns the ticker off.
+	Stop()
+}
+
+// OptionClock sets the clock progress bar tells the time by, instead of the system one.
+func OptionClock(clock Clock) Option {
+	return func(p *ProgressBar) {
+		p.config.clock = clock
+	}
+}
+
+// systemClock is the Clock of the system.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+// systemTicker is the Ticker of the system clock.
+type systemTicker struct {
+	t *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t systemTicker) Stop() {
+	t.t.Stop()
+}
@@ -0,0 +1,167 @@
+// Command progressbar copies its standard input to its standard output,
+// showing the progress on its standard error, like pv.
+//
+// Usage:
+//
+//	progressbar [flags] [file]
+//
+// If a file is given, it's read instead of the standard input.
+//
+// The flags, which can also be given with two dashes, are:
+//
+//	-size n
+//		the size of the input, in bytes or lines in line mode,
+//		taken from the input file if it's not given
+//	-name s
+//		the description of the bar
+//	-rate-limit n
+//		the limit of the rate of transfer, in bytes per second
+//	-line-mode
+//		count lines instead of bytes
+//	-width n
+//		the width of the bar, instead of the full width of the terminal
+//
+// Sizes can be given with K, M, G or T suffix for powers of 1024.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oerlikon/progressbar/v3"
+)
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(0)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "progressbar:", err)
+		os.Exit(1)
+	}
+}
+
+// run copies the input to stdout, showing the progress on stderr
+// in a bar configured like the one of progressbar.DefaultBytes.
+func run(args []string, stdin *os.File, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("progressbar", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var (
+		size      = flags.String("size", "", "the size of the input, in bytes or lines in line mode")
+		name      = flags.String("name", "", "the description of the bar")
+		rateLimit = flags.String("rate-limit", "", "the limit of the rate of transfer, in bytes per second")
+		lineMode  = flags.Bool("line-mode", false, "count lines instead of bytes")
+		width     = flags.Int("width", 0, "the width of the bar, instead of the full width of the terminal")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return errors.New("too many arguments")
+	}
+
+	in := stdin
+	if flags.NArg() == 1 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	max := int64(-1)
+	if *size != "" {
+		n, err := parseSize(*size)
+		if err != nil {
+			return fmt.Errorf("invalid size: %w", err)
+		}
+		max = n
+	} else if fi, err := in.Stat(); err == nil && fi.Mode().IsRegular() && !*lineMode {
+		max = fi.Size()
+	}
+
+	options := []progressbar.Option{
+		progressbar.OptionDescription(*name),
+		progressbar.OptionWriter(stderr),
+		progressbar.OptionThrottle(65 * time.Millisecond),
+		progressbar.OptionShowCount(),
+		progressbar.OptionSpinnerStyle(14),
+		progressbar.OptionOverflow(progressbar.OverflowGrow), // in case size is wrong
+	}
+	if *lineMode {
+		options = append(options, progressbar.OptionShowIts(), progressbar.OptionItsString("lines"))
+	} else {
+		options = append(options, progressbar.OptionShowBytes())
+	}
+	if *width > 0 {
+		options = append(options, progressbar.OptionWidth(*width))
+	} else {
+		options = append(options, progressbar.OptionWidth(10), progressbar.OptionFullWidth())
+	}
+	var limit []progressbar.Option
+	if *rateLimit != "" {
+		n, err := parseSize(*rateLimit)
+		if err != nil || n == 0 {
+			return fmt.Errorf("invalid rate limit: %s", *rateLimit)
+		}
+		limit = append(limit, progressbar.OptionRateLimit(n, 0))
+	}
+
+	var bar *progressbar.ProgressBar
+	var w io.Writer
+	if *lineMode {
+		bar = progressbar.New64(max, options...)
+		// the rate is limited in bytes, so by a hidden bar counting them
+		counter := progressbar.New64(-1, append(limit, progressbar.OptionVisible(false))...)
+		out := progressbar.NewWriter(stdout, counter)
+		w = io.MultiWriter(&out, lineCounter{bar})
+	} else {
+		bar = progressbar.New64(max, append(options, limit...)...)
+		out := progressbar.NewWriter(stdout, bar)
+		w = &out
+	}
+
+	if _, err := io.Copy(w, in); err != nil {
+		_ = bar.Stop()
+		return err
+	}
+	return bar.Finish()
+}
+
+// parseSize parses a nonnegative number with an optional K, M, G or T suffix.
+func parseSize(s string) (int64, error) {
+	shift := 0
+	if len(s) > 1 {
+		if i := strings.IndexByte("KMGT", s[len(s)-1]&^0x20); i >= 0 {
+			s, shift = s[:len(s)-1], 10*(i+1)
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > (1<<63-1)>>shift {
+		return 0, strconv.ErrRange
+	}
+	return n << shift, nil
+}
+
+// lineCounter is an io.Writer adding the number of lines written to a bar.
+// Errors of the bar are ignored, so as not to stop the data copied.
+type lineCounter struct {
+	bar *progressbar.ProgressBar
+}
+
+func (c lineCounter) Write(p []byte) (int, error) {
+	_ = c.bar.Add(bytes.Count(p, []byte{'\n'}))
+	return len(p), nil
+}
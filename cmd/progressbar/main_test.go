@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input")
+	assert.NoError(t, os.WriteFile(path, []byte(strings.Repeat("line\n", 100)), 0o644))
+
+	stdout, stderr := strings.Builder{}, strings.Builder{}
+	assert.NoError(t, run([]string{"--name", "input", "--width", "10", path}, nil, &stdout, &stderr))
+	assert.Equal(t, strings.Repeat("line\n", 100), stdout.String())
+	assert.True(t, strings.HasPrefix(stderr.String(), "input   0% |          | (0/500 B) "), stderr.String())
+	assert.Contains(t, stderr.String(), "input 100% |██████████| (500/500 B, ")
+
+	stdout.Reset()
+	stderr.Reset()
+	assert.NoError(t, run([]string{"-line-mode", "-size", "50", "-width", "10", path}, nil, &stdout, &stderr))
+	assert.Equal(t, 500, stdout.Len())
+	assert.Contains(t, stderr.String(), "100% |██████████| (100/100, ")
+	assert.Contains(t, stderr.String(), " lines/s)")
+
+	stdout.Reset()
+	start := time.Now()
+	assert.NoError(t, run([]string{"-rate-limit", "2K", path}, nil, &stdout, &stderr))
+	assert.Equal(t, 500, stdout.Len())
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+	assert.Error(t, run([]string{"-size", "x"}, nil, &stdout, &stderr))
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(b []byte) (int, error) {
+	return 0, errors.New("closed")
+}
+
+func TestRunFailingBar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input")
+	data := strings.Repeat("line\n", 20000) // copied in several chunks
+	assert.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+
+	for _, args := range [][]string{{path}, {"-line-mode", "-size", "100", path}} {
+		stdout := strings.Builder{}
+		run(args, nil, &stdout, failingWriter{})
+		assert.Equal(t, len(data), stdout.Len(), args)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for s, expected := range map[string]int64{"0": 0, "10": 10, "1K": 1024, "2m": 2 << 20, "1T": 1 << 40} {
+		n, err := parseSize(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, expected, n, s)
+	}
+	for _, s := range []string{"", "K", "-1", "1X", "9999999T"} {
+		_, err := parseSize(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,157 @@
+package progressbar
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// CopyFile copies file src to dst, showing the progress in a new bar,
+// configured as by DefaultBytes and described by the file's name,
+// followed by the options given. The file's mode is preserved.
+//
+// If ctx is canceled, copying stops and the error is returned.
+func CopyFile(ctx context.Context, dst, src string, options ...Option) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("copying %s: not a regular file", src)
+	}
+	bar := New64(fi.Size(), append(defaultBytesOptions(filepath.Base(src)), options...)...)
+	if err := copyFile(ctx, bar, dst, src, fi.Mode()); err != nil {
+		_ = bar.Stop()
+		return err
+	}
+	return bar.Finish()
+}
+
+// CopyTree copies directory src and everything in it to dst, showing the progress
+// of all the files in a new bar, configured as by DefaultBytes and described
+// by the name of the file being copied, followed by the options given.
+// The modes of the files and directories are preserved, and symbolic links
+// are copied as such.
+//
+// If ctx is canceled, copying stops and the error is returned.
+func CopyTree(ctx context.Context, dst, src string, options ...Option) error {
+	var total int64
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.Type().IsRegular():
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += fi.Size()
+		case !d.IsDir() && d.Type()&fs.ModeSymlink == 0:
+			return fmt.Errorf("copying %s: not a regular file", path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	bar := New64(total, append(defaultBytesOptions(""), options...)...)
+	if err := copyTree(ctx, bar, dst, src); err != nil {
+		_ = bar.Stop()
+		return err
+	}
+	return bar.Finish()
+}
+
+// copyTree copies directory src to dst, adding the bytes copied to bar.
+func copyTree(ctx context.Context, bar *ProgressBar, dst, src string) error {
+	type dir struct {
+		path string
+		mode fs.FileMode
+	}
+	var dirs []dir
+
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			// the directory has to be writable until its contents are copied
+			dirs = append(dirs, dir{target, fi.Mode()})
+			return os.MkdirAll(target, fi.Mode().Perm()|0o700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+		bar.SetDescription(rel)
+		return copyFile(ctx, bar, target, path, fi.Mode())
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, d := range slices.Backward(dirs) {
+		if err := os.Chmod(d.path, d.mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFile copies file src to dst with given mode, adding the bytes copied to bar.
+func copyFile(ctx context.Context, bar *ProgressBar, dst, src string, mode fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	r := NewReader(in, bar)
+	if _, err := io.Copy(out, contextReader{ctx, &r}); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(dst, mode) // not subject to umask
+}
+
+// contextReader is an io.Reader that fails once its context is canceled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,84 @@
+package progressbar
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	assert.NoError(t, os.WriteFile(src, []byte("content"), 0o640))
+	assert.NoError(t, os.Chmod(src, 0o751))
+
+	buf := strings.Builder{}
+	assert.NoError(t, CopyFile(context.Background(), dst, src,
+		OptionWriter(&buf)))
+	b, err := os.ReadFile(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(b))
+	fi, err := os.Stat(dst)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o751), fi.Mode())
+	assert.True(t, strings.HasPrefix(buf.String(), "src   0% |"), buf.String())
+	assert.Contains(t, buf.String(), "src 100% |")
+	assert.Contains(t, buf.String(), "| (7/7 B, ")
+	assert.True(t, strings.HasSuffix(buf.String(), "\n"), buf.String())
+}
+
+func TestCopyTree(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	assert.NoError(t, os.MkdirAll(filepath.Join(src, "sub"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "a"), []byte("aaa"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(src, "sub", "b"), []byte("bbbb"), 0o600))
+	assert.NoError(t, os.Symlink("a", filepath.Join(src, "link")))
+	assert.NoError(t, os.Chmod(filepath.Join(src, "sub"), 0o555))
+	defer os.Chmod(filepath.Join(src, "sub"), 0o755)
+
+	bar := New(7, OptionWriter(io.Discard))
+	assert.NoError(t, copyTree(context.Background(), bar, dst, src))
+	assert.Equal(t, 7.0, bar.State().CurrentBytes)
+	assert.Equal(t, filepath.Join("sub", "b"), bar.State().Description)
+
+	b, err := os.ReadFile(filepath.Join(dst, "sub", "b"))
+	assert.NoError(t, err)
+	assert.Equal(t, "bbbb", string(b))
+	link, err := os.Readlink(filepath.Join(dst, "link"))
+	assert.NoError(t, err)
+	assert.Equal(t, "a", link)
+	for name, mode := range map[string]os.FileMode{"a": 0o644, "sub": os.ModeDir | 0o555, "sub/b": 0o600} {
+		fi, err := os.Stat(filepath.Join(dst, name))
+		if assert.NoError(t, err) {
+			assert.Equal(t, mode, fi.Mode(), name)
+		}
+	}
+	os.Chmod(filepath.Join(dst, "sub"), 0o755)
+
+	buf := strings.Builder{}
+	assert.NoError(t, CopyTree(context.Background(), filepath.Join(dir, "again"), src,
+		OptionWriter(&buf), OptionThrottle(0)))
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "  0% |"), out)
+	assert.Contains(t, out, "a   0% |")
+	assert.Contains(t, out, "sub/b  42% |")
+	assert.Contains(t, out, "sub/b 100% |")
+	os.Chmod(filepath.Join(dir, "again", "sub"), 0o755)
+}
+
+func TestCopyCanceled(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	assert.NoError(t, os.WriteFile(src, []byte("content"), 0o644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, CopyFile(ctx, dst, src, OptionWriter(io.Discard)), context.Canceled)
+	assert.ErrorIs(t, CopyTree(ctx, dst, dir, OptionWriter(io.Discard)), context.Canceled)
+}
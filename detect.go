@@ -0,0 +1,57 @@
+package progressbar
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// OptionAutoDetect makes progress bar configure itself for its writer and environment.
+//
+// If the writer is a terminal, the bar spans its full width and makes use of ANSI codes,
+// unless TERM is "dumb". Otherwise the bar is hidden, except when running in CI
+// (the CI environment variable is set), where it's rendered as plain text at most
+// once a second. Colors, whether set with OptionStyles or OptionUseColorCodes,
+// are only written to terminals, unless NO_COLOR is set or TERM is "dumb",
+// or if FORCE_COLOR is set; color codes are stripped otherwise.
+//
+// Detection is done once all the other options are applied and takes precedence over them.
+func OptionAutoDetect() Option {
+	return func(p *ProgressBar) {
+		p.config.autoDetect = true
+	}
+}
+
+// autoDetect configures progress bar according to its writer and environment.
+func (p *ProgressBar) autoDetect() {
+	c := &p.config
+
+	terminal, dumb := isTerminal(c.writer), os.Getenv("TERM") == "dumb"
+	switch {
+	case terminal:
+		c.visible = true
+		c.fullWidth = true
+		c.useANSICodes = !dumb
+	case envFlag("CI"):
+		c.visible = true
+		c.fullWidth = false
+		c.useANSICodes = false
+		c.throttleInterval = max(c.throttleInterval, time.Second)
+	default:
+		c.visible = false
+	}
+	if !c.useANSICodes {
+		c.diffUpdates = false // made with ANSI codes too
+	}
+	c.stripColors = !colorsEnabled(c.writer)
+}
+
+// envFlag reports whether environment variable is set to something
+// other than an empty string, "0" or "false".
+func envFlag(name string) bool {
+	switch strings.ToLower(os.Getenv(name)) {
+	case "", "0", "false":
+		return false
+	}
+	return true
+}
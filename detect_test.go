@@ -0,0 +1,75 @@
+package progressbar
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func clearColorEnv(t *testing.T) {
+	for _, name := range []string{"NO_COLOR", "FORCE_COLOR", "TERM", "CI"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestAutoDetectTerminal(t *testing.T) {
+	clearColorEnv(t)
+	withTerminal(t)
+	bar := New(100, OptionAutoDetect(), OptionVisible(false), OptionWriter(io.Discard))
+	assert.True(t, bar.config.visible)
+	assert.True(t, bar.config.fullWidth)
+	assert.True(t, bar.config.useANSICodes)
+	assert.False(t, bar.config.stripColors)
+
+	t.Setenv("TERM", "dumb")
+	bar = New(100, OptionAutoDetect(), OptionHideCursor(), OptionDiffUpdates(), OptionWriter(io.Discard))
+	assert.True(t, bar.config.visible)
+	assert.False(t, bar.config.useANSICodes)
+	assert.False(t, bar.config.diffUpdates)
+	assert.False(t, bar.config.hideCursor)
+	assert.True(t, bar.config.stripColors)
+}
+
+func TestAutoDetectRedirected(t *testing.T) {
+	clearColorEnv(t)
+	bar := New(100, OptionAutoDetect(), OptionUseANSICodes(), OptionWriter(io.Discard))
+	assert.False(t, bar.config.visible)
+	assert.True(t, bar.config.stripColors)
+
+	t.Setenv("CI", "true")
+	buf := strings.Builder{}
+	bar = New(100, OptionAutoDetect(), OptionUseANSICodes(), OptionFullWidth(), OptionHideCursor(),
+		OptionDiffUpdates(), OptionWriter(&buf))
+	assert.True(t, bar.config.visible)
+	assert.False(t, bar.config.fullWidth)
+	assert.False(t, bar.config.useANSICodes)
+	assert.False(t, bar.config.diffUpdates)
+	assert.Equal(t, time.Second, bar.config.throttleInterval)
+	bar.Add(10)
+	bar.Finish()
+	assert.NotContains(t, buf.String(), "\033")
+}
+
+func TestAutoDetectColors(t *testing.T) {
+	clearColorEnv(t)
+	withTerminal(t)
+	t.Setenv("NO_COLOR", "1")
+	bar := New(100, OptionAutoDetect(), OptionDescription("[red]go"),
+		OptionUseColorCodes(), OptionWriter(io.Discard))
+	assert.True(t, strings.HasPrefix(bar.String(), "go   0% |"), bar.String())
+
+	t.Setenv("FORCE_COLOR", "1")
+	bar = New(100, OptionAutoDetect(), OptionDescription("[red]go"),
+		OptionUseColorCodes(), OptionWriter(io.Discard))
+	assert.True(t, strings.HasPrefix(bar.String(), "\033[31mgo   0% |"), bar.String())
+}
+
+func TestEnvFlag(t *testing.T) {
+	for value, expected := range map[string]bool{"": false, "0": false, "False": false, "1": true, "true": true} {
+		t.Setenv("PROGRESSBAR_TEST_FLAG", value)
+		assert.Equal(t, expected, envFlag("PROGRESSBAR_TEST_FLAG"), value)
+	}
+}
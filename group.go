@@ -0,0 +1,152 @@
+package progressbar
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Group runs tasks concurrently, like errgroup.Group, showing their progress.
+//
+// Its bar counts the tasks completed out of all, and is described by
+// the numbers of the ones running and queued. The tasks started with GoBar
+// also have bars of their own, which are cleared once they're finished,
+// or stopped telling that the task has failed. With OptionMulti, all the bars
+// are displayed at once, each on its own line.
+type Group struct {
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	options []Option
+	wg      sync.WaitGroup
+	sem     chan struct{}
+
+	errOnce sync.Once
+	err     error
+
+	mu      sync.Mutex
+	bar     *ProgressBar // created with the first task
+	queued  int
+	running int
+}
+
+// NewGroup creates a new Group and its context derived from ctx, which
+// is canceled once a task fails or Wait returns. The bars are configured
+// as by Default, followed by the options given.
+func NewGroup(ctx context.Context, options ...Option) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{
+		ctx:     ctx,
+		cancel:  cancel,
+		options: slices.Clip(append(defaultOptions(""), options...)), // appended to concurrently
+	}, ctx
+}
+
+// SetLimit limits the number of tasks running at once to n, or lifts the limit
+// if n is negative. It must not be called while there are tasks in the group.
+func (g *Group) SetLimit(n int) {
+	g.sem = nil
+	if n >= 0 {
+		g.sem = make(chan struct{}, n)
+	}
+}
+
+// Go runs task f in a new goroutine, once the number of tasks running
+// is below the limit. The first error returned by a task cancels
+// the group's context and is returned by Wait.
+func (g *Group) Go(f func(ctx context.Context) error) {
+	g.run(func() error {
+		return f(g.ctx)
+	})
+}
+
+// GoBar is like Go, but the task gets a bar of its own, with given description
+// and maximum, which is finished once the task is done, unless it has failed.
+func (g *Group) GoBar(description string, max int64, f func(ctx context.Context, bar *ProgressBar) error) {
+	g.run(func() error {
+		bar := New64(max, append(g.options, OptionDescription(description), OptionClearOnFinish())...)
+		if err := f(g.ctx, bar); err != nil {
+			_ = bar.Fail()
+			return err
+		}
+		return bar.Finish()
+	})
+}
+
+// Wait waits until all the tasks are done, and returns the first error
+// returned by a task, if any.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel(g.err)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.bar != nil {
+		if g.err != nil {
+			_ = g.bar.Fail()
+		} else {
+			_ = g.bar.Finish()
+		}
+	}
+	return g.err
+}
+
+// run runs task f in a new goroutine, accounting for it in the group's bar.
+func (g *Group) run(f func() error) {
+	g.mu.Lock()
+	g.queued++
+	if g.bar == nil {
+		g.bar = New64(1, append(g.options, OptionDescription(g.description()))...)
+	} else {
+		_ = g.bar.AddMax(1)
+		g.update()
+	}
+	g.mu.Unlock()
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+
+		if g.sem != nil {
+			g.sem <- struct{}{}
+			defer func() { <-g.sem }()
+		}
+		g.mu.Lock()
+		g.queued--
+		g.running++
+		g.update()
+		g.mu.Unlock()
+
+		err := f()
+
+		g.mu.Lock()
+		g.running--
+		_ = g.bar.Add(1)
+		g.update()
+		g.mu.Unlock()
+
+		if err != nil {
+			g.errOnce.Do(func() {
+				g.err = err
+				g.cancel(err)
+			})
+		}
+	}()
+}
+
+// update describes the group's bar by the numbers of tasks running
+// and queued. it must be called with the group's lock acquired.
+func (g *Group) update() {
+	g.bar.SetDescription(g.description())
+}
+
+// description returns the numbers of tasks running and queued.
+// it must be called with the group's lock acquired.
+func (g *Group) description() string {
+	d := strconv.Itoa(g.running) + " running"
+	if g.queued > 0 {
+		d += ", " + strconv.Itoa(g.queued) + " queued"
+	}
+	return d
+}
@@ -0,0 +1,93 @@
+package progressbar
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup(t *testing.T) {
+	buf := strings.Builder{}
+	g, _ := NewGroup(context.Background(), OptionMulti(NewMulti(&buf)), OptionThrottle(0))
+	g.SetLimit(2)
+
+	var running, peak atomic.Int32
+	for range 5 {
+		g.GoBar("task", 10, func(ctx context.Context, bar *ProgressBar) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			for range 10 {
+				bar.Add(1)
+			}
+			return nil
+		})
+	}
+	g.Go(func(ctx context.Context) error { return nil })
+	assert.NoError(t, g.Wait())
+	assert.LessOrEqual(t, peak.Load(), int32(2))
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "\033[2K0 running, 1 queued   0% |"), "%q", out)
+	assert.Contains(t, out, "task 100% |")
+	last := out[strings.LastIndex(out, "\r\033["):] // the task bars are cleared
+	assert.True(t, strings.HasPrefix(last, "\r\033[1A\033[2K0 running 100% |"), "%q", last)
+	assert.Contains(t, last, "| (6/6, ")
+	assert.Equal(t, 1, strings.Count(last, "\n"))
+}
+
+func TestGroupError(t *testing.T) {
+	buf := strings.Builder{}
+	m := NewMulti(&buf)
+	g, ctx := NewGroup(context.Background(), OptionMulti(m))
+	errFailed := errors.New("failed")
+	g.GoBar("bad", 10, func(ctx context.Context, bar *ProgressBar) error {
+		bar.Add(3)
+		return errFailed
+	})
+	g.GoBar("good", 10, func(ctx context.Context, bar *ProgressBar) error {
+		<-ctx.Done()
+		return nil
+	})
+	assert.ErrorIs(t, g.Wait(), errFailed)
+	assert.ErrorIs(t, context.Cause(ctx), errFailed)
+
+	assert.Len(t, m.bars, 2) // the group's one and the failed task's one
+	assert.True(t, m.bars[0].State().Failed)
+	s := m.bars[1].State()
+	assert.True(t, s.Failed)
+	assert.Equal(t, "bad", s.Description)
+	assert.Contains(t, m.bars[1].String(), "| (3/10, ")
+	assert.True(t, strings.HasSuffix(m.bars[1].String(), " failed "), m.bars[1].String())
+}
+
+func TestGroupDescriptions(t *testing.T) {
+	g, _ := NewGroup(context.Background(), OptionWriter(io.Discard))
+	bars := make([]*ProgressBar, 8)
+	var ready sync.WaitGroup
+	ready.Add(len(bars))
+	for i := range bars {
+		g.GoBar("task "+strconv.Itoa(i), 1, func(ctx context.Context, bar *ProgressBar) error {
+			bars[i] = bar
+			ready.Done()
+			ready.Wait() // until all the bars are created
+			return nil
+		})
+	}
+	assert.NoError(t, g.Wait())
+	for i, bar := range bars {
+		assert.Equal(t, "task "+strconv.Itoa(i), bar.State().Description)
+	}
+}
@@ -0,0 +1,179 @@
+package progressbar
+
+import (
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Transport is an http.RoundTripper showing the progress of uploading
+// request bodies and downloading response bodies.
+//
+// The bars are configured as by DefaultBytes and described by
+// the last element of the URL's path, followed by Options. An upload bar
+// is finished once the response is received, or failed if the request fails.
+type Transport struct {
+	// Base is the RoundTripper making the requests,
+	// http.DefaultTransport if nil.
+	Base http.RoundTripper
+
+	// Multi, if not nil, is where the bars are displayed,
+	// until they're finished.
+	Multi *Multi
+
+	// Options are applied to each bar.
+	Options []Option
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	name := path.Base(req.URL.Path)
+	if name == "/" || name == "." {
+		name = req.URL.Host
+	}
+
+	var upload *uploadBody
+	if req.Body != nil && req.Body != http.NoBody {
+		size := req.ContentLength
+		if size <= 0 {
+			size = -1 // zero means unknown unless there's no body
+		}
+		bar := New64(size, append(defaultBytesOptions("uploading "+name), t.options()...)...)
+		upload = &uploadBody{Reader: NewReader(req.Body, bar)}
+		req = req.Clone(req.Context())
+		req.Body = upload
+	}
+
+	resp, err := base.RoundTrip(req)
+	if upload != nil {
+		upload.done(err)
+	}
+	if err != nil || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, err
+	}
+	resp.Body = NewResponseReader(resp, append([]Option{OptionDescription("downloading " + name)}, t.options()...)...)
+	return resp, nil
+}
+
+// options returns the options of the bars, other than the defaults and description.
+func (t *Transport) options() []Option {
+	if t.Multi == nil {
+		return t.Options
+	}
+	return append([]Option{OptionMulti(t.Multi), OptionClearOnFinish()}, t.Options...)
+}
+
+// uploadBody is a request body whose bar is finished once it's closed
+// and the response is received, or failed if the request fails.
+type uploadBody struct {
+	Reader
+
+	mu        sync.Mutex
+	closed    bool
+	responded bool // the round trip is over
+	failed    bool
+}
+
+// Close closes the body without finishing the bar until the response is received.
+func (b *uploadBody) Close() error {
+	var err error
+	if closer, ok := b.r.(io.Closer); ok {
+		err = closer.Close()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	if b.responded && !b.failed {
+		_ = b.bar.Finish()
+	}
+	return err
+}
+
+// done finishes the bar once the round trip is over, if the body is closed,
+// or fails it if the round trip has failed with err.
+func (b *uploadBody) done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.responded, b.failed = true, err != nil
+	if b.failed {
+		_ = b.bar.Fail()
+	} else if b.closed {
+		_ = b.bar.Finish()
+	}
+}
+
+// NewResponseReader creates a Reader of resp's body with a new progress bar,
+// configured as by DefaultBytes, followed by the options given.
+//
+// The bar is sized from Content-Length, or is a spinner if it's unknown.
+// For a partial content response, e.g. to a request resuming a download
+// with a Range header, the bar spans the whole content and starts
+// at the offset from Content-Range.
+func NewResponseReader(resp *http.Response, options ...Option) *Reader {
+	max, offset := responseSize(resp)
+	if offset > 0 {
+		options = append([]Option{OptionStartOffset(offset)}, options...)
+	}
+	bar := New64(max, append(defaultBytesOptions(""), options...)...)
+	r := NewReader(resp.Body, bar)
+	return &r
+}
+
+// responseSize returns the size of resp's content, or -1 if it's unknown,
+// and the offset of its body in it.
+func responseSize(resp *http.Response) (size, offset int64) {
+	size = resp.ContentLength
+	if resp.StatusCode != http.StatusPartialContent {
+		return max(size, -1), 0
+	}
+	first, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+	if !ok {
+		return max(size, -1), 0
+	}
+	switch {
+	case total >= 0:
+		return total, first
+	case size >= 0:
+		return first + size, first
+	}
+	return -1, first
+}
+
+// parseContentRange parses Content-Range header value of the form
+// "bytes first-last/total" and returns the first byte position and
+// the total length, which is -1 if it's given as "*".
+func parseContentRange(s string) (first, total int64, ok bool) {
+	s, ok = strings.CutPrefix(s, "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	span, length, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return 0, 0, false
+	}
+	from, _, ok := strings.Cut(span, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	first, err := strconv.ParseInt(from, 10, 64)
+	if err != nil || first < 0 {
+		return 0, 0, false
+	}
+	if length == "*" {
+		return first, -1, true
+	}
+	total, err = strconv.ParseInt(length, 10, 64)
+	if err != nil || total < first {
+		return 0, 0, false
+	}
+	return first, total, true
+}
@@ -0,0 +1,152 @@
+package progressbar
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewResponseReader(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "content", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	r := NewResponseReader(resp, OptionWriter(io.Discard))
+	assert.Equal(t, int64(1000), r.bar.Max64())
+	b, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(b))
+	assert.Equal(t, 1000.0, r.bar.State().CurrentBytes)
+	assert.NoError(t, r.Close())
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("Range", "bytes=600-")
+	resp, err = http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	r = NewResponseReader(resp, OptionWriter(io.Discard))
+	assert.Equal(t, int64(1000), r.bar.Max64())
+	s := r.bar.State()
+	assert.Equal(t, 600.0, s.CurrentBytes)
+	assert.Equal(t, 600.0, s.OffsetBytes)
+	b, err = io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, content[600:], string(b))
+	assert.Equal(t, 1000.0, r.bar.State().CurrentBytes)
+	assert.NoError(t, r.Close())
+}
+
+func TestNewResponseReaderUnknownLength(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush() // chunked, with no Content-Length
+		io.WriteString(w, "content")
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	r := NewResponseReader(resp, OptionWriter(io.Discard))
+	assert.Equal(t, int64(-1), r.bar.State().Max)
+	b, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(b))
+	assert.NoError(t, r.Close())
+}
+
+func TestResponseSize(t *testing.T) {
+	tests := []struct {
+		status       int
+		length       int64
+		contentRange string
+		size, offset int64
+	}{
+		{200, 100, "", 100, 0},
+		{200, -1, "", -1, 0},
+		{206, 50, "bytes 50-99/100", 100, 50},
+		{206, 50, "bytes 50-99/*", 100, 50},
+		{206, -1, "bytes 50-99/*", -1, 50},
+		{206, 50, "bytes */100", 50, 0},
+		{206, 50, "items 50-99/100", 50, 0},
+	}
+	for _, test := range tests {
+		resp := &http.Response{StatusCode: test.status, ContentLength: test.length, Header: http.Header{}}
+		resp.Header.Set("Content-Range", test.contentRange)
+		size, offset := responseSize(resp)
+		assert.Equal(t, test.size, size, test.contentRange)
+		assert.Equal(t, test.offset, offset, test.contentRange)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+		w.Write(b)
+	}))
+	defer server.Close()
+
+	buf := strings.Builder{}
+	client := &http.Client{Transport: &Transport{
+		Multi:   NewMulti(&buf),
+		Options: []Option{OptionThrottle(0)},
+	}}
+	resp, err := client.Post(server.URL+"/echo", "text/plain", strings.NewReader("content"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	b, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(b))
+	assert.NoError(t, resp.Body.Close())
+
+	out := buf.String()
+	assert.Contains(t, out, "\033[2Kuploading echo 100% |")
+	assert.Contains(t, out, "| (7/7 B, ")
+	assert.Contains(t, out, "\033[2Kdownloading echo 100% |")
+	assert.True(t, strings.HasSuffix(out, "\r\033[1A\033[J"), "%q", out) // the bars are removed
+	assert.Empty(t, client.Transport.(*Transport).Multi.bars)
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTransportError(t *testing.T) {
+	errFailed := errors.New("failed")
+	m := NewMulti(io.Discard)
+	client := &http.Client{Transport: &Transport{
+		Base: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req.Body.Read(make([]byte, 3))
+			req.Body.Close()
+			return nil, errFailed
+		}),
+		Multi: m,
+	}}
+	_, err := client.Post("http://localhost/upload", "text/plain", strings.NewReader("content"))
+	assert.ErrorIs(t, err, errFailed)
+	if assert.Len(t, m.bars, 1) {
+		s := m.bars[0].State()
+		assert.Equal(t, "uploading upload", s.Description)
+		assert.Equal(t, int64(3), s.Current)
+		assert.True(t, s.Failed)
+	}
+}
@@ -0,0 +1,57 @@
+package progressbar
+
+import (
+	"iter"
+	"maps"
+	"slices"
+)
+
+// Seq returns a sequence of the values of seq, which adds one to bar for each
+// value once it's been processed by the loop body. When the loop ends, the bar
+// is finished, or stopped at its current state if the loop is broken early.
+func Seq[V any](seq iter.Seq[V], bar *ProgressBar) iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for v := range seq {
+			if !yield(v) {
+				_ = bar.Stop()
+				return
+			}
+			_ = bar.Add(1)
+		}
+		_ = bar.Finish()
+	}
+}
+
+// Seq2 is like Seq for sequences of pairs of values.
+func Seq2[K, V any](seq iter.Seq2[K, V], bar *ProgressBar) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for k, v := range seq {
+			if !yield(k, v) {
+				_ = bar.Stop()
+				return
+			}
+			_ = bar.Add(1)
+		}
+		_ = bar.Finish()
+	}
+}
+
+// Slice returns a sequence of the indices and elements of s, like slices.All,
+// showing the progress of the loop in a new bar, configured as by Default,
+// followed by the options given. The bar is created once the loop starts,
+// and finished or stopped as by Seq.
+func Slice[S ~[]E, E any](s S, options ...Option) iter.Seq2[int, E] {
+	return func(yield func(int, E) bool) {
+		bar := New(len(s), append(defaultOptions(""), options...)...)
+		Seq2(slices.All(s), bar)(yield)
+	}
+}
+
+// Map returns a sequence of the keys and values of m, like maps.All,
+// showing the progress of the loop as by Slice.
+func Map[M ~map[K]V, K comparable, V any](m M, options ...Option) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		bar := New(len(m), append(defaultOptions(""), options...)...)
+		Seq2(maps.All(m), bar)(yield)
+	}
+}
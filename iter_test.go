@@ -0,0 +1,66 @@
+package progressbar
+
+import (
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeq(t *testing.T) {
+	bar := New(3, OptionWriter(io.Discard))
+	var values []int
+	for v := range Seq(slices.Values([]int{1, 2, 3}), bar) {
+		assert.Equal(t, float64(len(values)), bar.State().CurrentBytes)
+		values = append(values, v)
+	}
+	assert.Equal(t, []int{1, 2, 3}, values)
+	s := bar.State()
+	assert.Equal(t, 3.0, s.CurrentBytes)
+	assert.True(t, s.Finished)
+	assert.False(t, s.Stopped)
+
+	bar = New(3, OptionWriter(io.Discard))
+	for v := range Seq(slices.Values([]int{1, 2, 3}), bar) {
+		if v == 2 {
+			break
+		}
+	}
+	s = bar.State()
+	assert.Equal(t, 1.0, s.CurrentBytes)
+	assert.True(t, s.Stopped)
+}
+
+func TestSlice(t *testing.T) {
+	buf := strings.Builder{}
+	items := []string{"a", "b", "c", "d"}
+	var got []string
+	for i, item := range Slice(items, OptionWriter(&buf), OptionThrottle(0)) {
+		assert.Equal(t, items[i], item)
+		got = append(got, item)
+	}
+	assert.Equal(t, items, got)
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "  0% |"), out)
+	assert.Contains(t, out, " 50% |")
+	assert.Contains(t, out, "100% |")
+	assert.Contains(t, out, "(4/4, ")
+}
+
+func TestMap(t *testing.T) {
+	buf := strings.Builder{}
+	m := map[string]int{"a": 1, "b": 2, "c": 3}
+	n := 0
+	for range Map(m, OptionWriter(&buf)) {
+		n++
+		if n == 2 {
+			break
+		}
+	}
+	out := buf.String()
+	assert.Contains(t, out, "(1/3, ")
+	assert.NotContains(t, out, "100% |")
+	assert.True(t, strings.HasSuffix(out, "\n"), out)
+}
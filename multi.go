@@ -0,0 +1,119 @@
+package progressbar
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Multi displays several progress bars at once, one per line,
+// in the order they're created. It relies on ANSI codes
+// to redraw the lines, so it's meant for terminals.
+//
+// It is safe for concurrent use by multiple goroutines.
+type Multi struct {
+	mu     sync.Mutex
+	writer io.Writer
+	flush  func(w io.Writer) error
+
+	bars  []*ProgressBar
+	lines [][]byte // the bars as rendered last
+	drawn int      // number of lines drawn last
+	frame []byte
+}
+
+// NewMulti creates a new Multi writing to w.
+func NewMulti(w io.Writer) *Multi {
+	return &Multi{writer: w, flush: defaultFlush(w)}
+}
+
+// OptionMulti makes progress bar displayed in m, after the bars
+// already there, instead of being written to its own writer.
+//
+// With OptionClearOnFinish, the bar is removed from m once finished.
+func OptionMulti(m *Multi) Option {
+	return func(p *ProgressBar) {
+		p.config.multi = m
+	}
+}
+
+// add appends bar p to the ones displayed.
+func (m *Multi) add(p *ProgressBar) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.bars = append(m.bars, p)
+	m.lines = append(m.lines, nil)
+}
+
+// update sets the line of bar p and redraws the bars.
+// It's called with p's lock acquired.
+func (m *Multi) update(p *ProgressBar, line []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if i := slices.Index(m.bars, p); i >= 0 {
+		m.lines[i] = append(m.lines[i][:0], line...)
+	}
+	return m.draw()
+}
+
+// remove stops displaying bar p and redraws the rest.
+// It's called with p's lock acquired.
+func (m *Multi) remove(p *ProgressBar) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if i := slices.Index(m.bars, p); i >= 0 {
+		m.bars = slices.Delete(m.bars, i, i+1)
+		m.lines = slices.Delete(m.lines, i, i+1)
+	}
+	return m.draw()
+}
+
+// printAbove prints b, which is expected to end with a newline, in place of the bars
+// drawn last, and then redraws the bars below it.
+func (m *Multi) printAbove(b []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f := m.frame[:0]
+	if m.drawn > 0 {
+		f = strconv.AppendInt(append(f, "\r\033["...), int64(m.drawn), 10)
+		f = append(f, "A\033[J"...)
+		m.drawn = 0
+	}
+	m.frame = append(f, b...)
+	return m.draw()
+}
+
+// draw redraws the bars in place of the ones drawn last, after whatever
+// is in the frame already, leaving the cursor on the line below. this function
+// is not thread-safe, so it must be called with an acquired lock.
+func (m *Multi) draw() error {
+	f := m.frame
+	if m.drawn > 0 {
+		f = strconv.AppendInt(append(f, "\r\033["...), int64(m.drawn), 10)
+		f = append(f, 'A')
+	}
+	for _, line := range m.lines {
+		f = append(append(append(f, "\033[2K"...), line...), '\n')
+	}
+	if len(m.lines) < m.drawn {
+		f = append(f, "\033[J"...) // erase the lines left over
+	}
+	m.drawn = len(m.lines)
+	m.frame = f[:0]
+
+	if _, err := m.writer.Write(f); err != nil {
+		return fmt.Errorf("writing progress bar: %w", err)
+	}
+	if m.flush != nil {
+		if err := m.flush(m.writer); err != nil {
+			return fmt.Errorf("flushing progress bar: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package progressbar
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMulti(t *testing.T) {
+	buf := strings.Builder{}
+	m := NewMulti(&buf)
+	bar1 := New(100, OptionWidth(10), OptionMulti(m))
+	bar2 := New(100, OptionWidth(10), OptionDescription("two"), OptionMulti(m), OptionClearOnFinish())
+	New(100, OptionMulti(m), OptionVisible(false))
+	bar1.Add(50)
+	bar2.Finish()
+	bar1.Finish()
+	expect := "" +
+		"\033[2K  0% |          | \n" +
+		"\r\033[1A" +
+		"\033[2K  0% |          | \n" +
+		"\033[2Ktwo   0% |          | \n" +
+		"\r\033[2A" +
+		"\033[2K 50% |█████     | \n" +
+		"\033[2Ktwo   0% |          | \n" +
+		"\r\033[2A" +
+		"\033[2K 50% |█████     | \n" +
+		"\033[J" +
+		"\r\033[1A" +
+		"\033[2K100% |██████████| \n"
+	assert.Equal(t, expect, buf.String())
+}
+
+func TestMultiSlogHandler(t *testing.T) {
+	buf := strings.Builder{}
+	m := NewMulti(&buf)
+	bar1 := New(100, OptionWidth(10), OptionMulti(m))
+	New(100, OptionWidth(10), OptionDescription("two"), OptionMulti(m))
+	logger := slog.New(newTestSlogHandler(bar1, false))
+	buf.Reset()
+	logger.Info("hello", "n", 1)
+	bar1.Finish()
+	logger.Info("bye")
+	expect := "" +
+		"\r\033[2A\033[J" +
+		"level=INFO msg=hello n=1\n" +
+		"\033[2K  0% |          | \n" +
+		"\033[2Ktwo   0% |          | \n" +
+		"\r\033[2A" +
+		"\033[2K100% |██████████| \n" +
+		"\033[2Ktwo   0% |          | \n" +
+		"\r\033[2A\033[J" +
+		"level=INFO msg=bye\n" +
+		"\033[2K100% |██████████| \n" +
+		"\033[2Ktwo   0% |          | \n"
+	assert.Equal(t, expect, buf.String())
+}
@@ -3,15 +3,18 @@
 package progressbar
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"math"
 	"os"
-	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode/utf8"
 
@@ -20,20 +23,67 @@ import (
 	"golang.org/x/term"
 )
 
+var (
+	// ErrExceedsMax is returned when progress bar's value would exceed its maximum,
+	// unless a different overflow policy is set with OptionOverflow.
+	ErrExceedsMax = errors.New("current number exceeds max")
+
+	// ErrNegativeMax is returned when progress bar's maximum is set to a negative value.
+	ErrNegativeMax = errors.New("max must be nonnegative")
+
+	// ErrInvalidSpinner is returned by NewE for an unknown spinner style.
+	ErrInvalidSpinner = errors.New("invalid spinner type, must be 9 or 14 or 59")
+
+	// ErrFinished is returned when progress is made after Finish or Stop.
+	ErrFinished = errors.New("progress bar is finished")
+)
+
 // ProgressBar is a simple customizable progress bar.
 // It is safe for concurrent use by multiple goroutines.
 type ProgressBar struct {
 	sync.Mutex
 	state  state
 	config config
+
+	// how much more addPending can add before the bar is full, or zero while
+	// the state is being changed. what it has added is headroom minus room
+	room atomic.Int64
+	// how much could be added before the bar is full, as of the last update,
+	// guarded by the lock
+	headroom int64
+	// time in unix nanoseconds before which the bar isn't refreshed
+	nextRender atomic.Int64
+	// set while pending increments are due to be rendered by refresh
+	refreshing atomic.Bool
 }
 
 // State is a summary of progress bar's current position.
+//
+// The rate and the remaining time are the same as the bar displays, whether
+// it's configured to show them or not. The remaining time is in whole seconds.
+//
+// It can be marshaled, e.g. with encoding/json, and passed to OptionRestore
+// to continue the progress in another bar, possibly in another process.
 type State struct {
-	CurrentPercent float64
-	CurrentBytes   float64
-	SecondsSince   float64
-	SecondsLeft    float64
+	Max            int64   `json:"max"` // -1 for spinners
+	Current        int64   `json:"current"`
+	CurrentPercent float64 `json:"current_percent"`
+	CurrentBytes   float64 `json:"current_bytes"`
+	SecondsSince   float64 `json:"seconds_since"`
+	SecondsLeft    float64 `json:"seconds_left"`
+	Rate           float64 `json:"rate"` // per second
+
+	// the part of CurrentBytes which was there before the bar
+	// has started, and is not taken into account in its rate
+	OffsetBytes float64 `json:"offset_bytes,omitempty"`
+
+	Description string    `json:"description,omitempty"`
+	StartTime   time.Time `json:"start_time"`
+
+	Finished bool `json:"finished,omitempty"`
+	Stopped  bool `json:"stopped,omitempty"`
+	Failed   bool `json:"failed,omitempty"`
+	Paused   bool `json:"paused,omitempty"`
 }
 
 type state struct {
@@ -44,6 +94,11 @@ type state struct {
 
 	lastShown time.Time
 	startTime time.Time
+	offset    int64 // counted before the start, excluded from the rate
+
+	pausedAt   time.Time // zero unless paused
+	rolledBack bool      // the value has been decreased last
+	failed     bool
 
 	counterTime         time.Time
 	counterNumSinceLast int64
@@ -54,8 +109,22 @@ type state struct {
 	currentBytes float64
 	finished     bool
 	stopped      bool
+	closed       bool // the line with the bar has been left
+
+	cursorHidden bool
+	sigwatch     *sigwatch // restores the cursor on interrupt
+
+	// reusable buffers for rendering
+	line     []byte // the bar as rendered last
+	stats    []byte
+	brackets []byte
+	frame    []byte // output to be written at once
 
-	rendered string
+	// the line as written last and its cells, for diff updates
+	written      []byte
+	cells        []cell
+	writtenCells []cell
+	diffable     bool
 }
 
 type config struct {
@@ -64,15 +133,30 @@ type config struct {
 	maxHumanizedSuffix string
 	width              int
 	writer             io.Writer
-	now                func() time.Time
+	clock              Clock
 	theme              Theme
 	description        string
 	iterationString    string
 	ignoreLength       bool // ignoreLength if max bytes not known
+	overflow           Overflow
+
+	// the counter's value at the start, the part of it excluded from
+	// the rate, and the time that has already elapsed by then
+	initial int64
+	offset  int64
+	elapsed time.Duration
 
 	// whether the output is expected to contain color codes
 	colorCodes bool
 
+	// whether the color codes should be removed instead
+	stripColors bool
+
+	// styles of the elements, only applied if styled is true
+	styles    Styles
+	stylesSet bool
+	styled    bool
+
 	// show rate of change in kB/sec or MB/sec
 	showBytes bool
 
@@ -80,6 +164,9 @@ type config struct {
 	showIts   bool
 	showCount bool
 
+	// tell that progress has been rolled back
+	showRollback bool
+
 	// always display total rate
 	totalRate bool
 
@@ -110,10 +197,49 @@ type config struct {
 	// whether the render function should make use of ANSI codes to reduce console I/O
 	useANSICodes bool
 
-	// whether the getStringWidth function should be more rigorous
+	// whether to only write the changed parts of the line
+	diffUpdates bool
+
+	// flushes the writer after each frame, if not nil
+	flush    func(w io.Writer) error
+	flushSet bool
+
+	// whether to hide the cursor while the bar is active
+	hideCursor bool
+
+	// whether to stop the bar on interrupt, and what to do then
+	handleInterrupt bool
+	onInterrupt     func(sig os.Signal)
+
+	// whether the displayWidth function should be more rigorous
 	trickyWidths bool
+
+	segments segments
+
+	// whether to configure the bar according to the writer and environment
+	autoDetect bool
+
+	// the container the bar is displayed in instead of the writer, if any
+	multi *Multi
+
+	// limits the rate of Reader and Writer, if not nil
+	limiter *limiter
 }
 
+// Overflow is what progress bar does when its value would exceed its maximum.
+type Overflow int
+
+const (
+	// OverflowError leaves the value as it is and returns ErrExceedsMax.
+	OverflowError Overflow = iota
+	// OverflowClamp sets the value to the maximum.
+	OverflowClamp
+	// OverflowGrow raises the maximum to the value.
+	OverflowGrow
+	// OverflowUnknownLength turns the bar into a spinner.
+	OverflowUnknownLength
+)
+
 // Theme defines the elements of a progress bar.
 type Theme struct {
 	Saucer        string
@@ -214,6 +340,35 @@ func OptionShowRemaining() Option {
 	}
 }
 
+// OptionOverflow sets what progress bar does when its value would exceed
+// its maximum, the default being OverflowError.
+func OptionOverflow(overflow Overflow) Option {
+	return func(p *ProgressBar) {
+		p.config.overflow = overflow
+	}
+}
+
+// OptionStartOffset makes progress bar start at offset, e.g. to continue
+// an interrupted download. The offset is not taken into account in the rate
+// and remaining time estimate.
+func OptionStartOffset(offset int64) Option {
+	return func(p *ProgressBar) {
+		p.config.initial = offset
+		p.config.offset = offset
+		p.config.elapsed = 0
+	}
+}
+
+// OptionRestore makes progress bar start where the one that s was
+// taken of was, including the time elapsed.
+func OptionRestore(s State) Option {
+	return func(p *ProgressBar) {
+		p.config.initial = int64(s.CurrentBytes)
+		p.config.offset = int64(s.OffsetBytes)
+		p.config.elapsed = time.Duration(s.SecondsSince * float64(time.Second))
+	}
+}
+
 // OptionShowCount enables display of current count out of total.
 func OptionShowCount() Option {
 	return func(p *ProgressBar) {
@@ -228,6 +383,14 @@ func OptionShowIts() Option {
 	}
 }
 
+// OptionShowRollback makes progress bar tell when its value
+// has been decreased, until it's increased again.
+func OptionShowRollback() Option {
+	return func(p *ProgressBar) {
+		p.config.showRollback = true
+	}
+}
+
 // OptionItsString sets what the iterations are called.
 // The default is "it" which would display "it/s".
 func OptionItsString(its string) Option {
@@ -245,6 +408,7 @@ func OptionTotalRate() Option {
 }
 
 // OptionThrottle enables minimum time intervals between refreshing the progress bar.
+// The updates made meanwhile are shown once the interval passes, even if no more follow.
 //
 // Default interval is zero which makes progress bar refresh on every update.
 func OptionThrottle(interval time.Duration) Option {
@@ -276,6 +440,60 @@ func OptionUseANSICodes() Option {
 	}
 }
 
+// OptionDiffUpdates makes progress bar only rewrite the parts of the line that have changed
+// since the previous update, moving the cursor over the rest. The whole line is rewritten
+// when its layout changes, and always if it contains color codes or styles.
+//
+// Useful over slow connections, implies OptionUseANSICodes. It is turned off
+// along with ANSI codes by OptionAutoDetect.
+func OptionDiffUpdates() Option {
+	return func(p *ProgressBar) {
+		p.config.diffUpdates = true
+		p.config.useANSICodes = true
+	}
+}
+
+// OptionFlush sets the function called to flush the writer after each write.
+// A nil function disables flushing.
+//
+// By default writers having a Flush() error method are flushed with it,
+// and files other than terminals and regular files are synced.
+func OptionFlush(flush func(w io.Writer) error) Option {
+	return func(p *ProgressBar) {
+		p.config.flush = flush
+		p.config.flushSet = true
+	}
+}
+
+// OptionHideCursor makes progress bar hide the terminal cursor while it's displayed.
+// It's only hidden with OptionUseANSICodes or on terminals, and with OptionAutoDetect,
+// only if ANSI codes are detected to be in use.
+//
+// The cursor is shown again when the bar is finished, stopped or cleared,
+// as well as when the process receives an interrupt or termination signal.
+// The signal is then raised again to let the process terminate as usual, so
+// if the application is notified of it as well, e.g. by signal.NotifyContext,
+// it receives the signal twice. Such applications should rather use
+// OptionHandleInterrupt, whose fn is called instead of raising the signal.
+func OptionHideCursor() Option {
+	return func(p *ProgressBar) {
+		p.config.hideCursor = true
+	}
+}
+
+// OptionHandleInterrupt makes progress bar handle interrupt and termination signals
+// while it's active. Upon a signal the bar is stopped, a new line is started and
+// the cursor is shown if it has been hidden. Then fn is called if it's not nil,
+// otherwise the signal is raised again to let the process terminate as usual.
+//
+// The handler is removed once the bar is finished or stopped.
+func OptionHandleInterrupt(fn func(sig os.Signal)) Option {
+	return func(p *ProgressBar) {
+		p.config.handleInterrupt = true
+		p.config.onInterrupt = fn
+	}
+}
+
 // New constructs a new instance of ProgressBar with specified options.
 //
 // With max == -1 it creates a spinner.
@@ -287,9 +505,17 @@ func New(max int, options ...Option) *ProgressBar {
 //
 // With max == -1 it creates a spinner.
 func New64(max int64, options ...Option) *ProgressBar {
+	b, err := newProgressBar(max, options)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func newProgressBar(max int64, options []Option) (*ProgressBar, error) {
 	b := ProgressBar{config: config{
 		writer:           os.Stdout,
-		now:              time.Now,
+		clock:            systemClock{},
 		theme:            defaultTheme,
 		iterationString:  "it",
 		width:            40,
@@ -305,8 +531,21 @@ func New64(max int64, options ...Option) *ProgressBar {
 		o(&b)
 	}
 
+	if b.config.multi != nil {
+		b.config.writer = b.config.multi.writer
+		b.config.diffUpdates = false // the line is redrawn by the container
+	}
+
+	if b.config.autoDetect {
+		b.autoDetect()
+	}
+	if b.config.hideCursor {
+		// with an ANSI code, so only where they're understood
+		b.config.hideCursor = b.config.useANSICodes || !b.config.autoDetect && isTerminal(b.config.writer)
+	}
+
 	if b.config.spinnerType != 9 && b.config.spinnerType != 14 && b.config.spinnerType != 59 {
-		panic("invalid spinner type, must be 9 or 14 or 59")
+		return nil, ErrInvalidSpinner
 	}
 
 	// ignoreLength if max bytes not known
@@ -317,12 +556,42 @@ func New64(max int64, options ...Option) *ProgressBar {
 	}
 
 	b.config.maxHumanized, b.config.maxHumanizedSuffix = humanizeBytes(float64(b.config.max))
+	b.config.styled = b.config.stylesSet && colorsEnabled(b.config.writer)
+	if !b.config.flushSet {
+		b.config.flush = defaultFlush(b.config.writer)
+	}
+	if b.config.multi != nil && b.config.visible {
+		b.config.multi.add(&b)
+	}
 	b.checkTrickyWidths()
+	b.updateSegments()
+
+	if b.config.limiter != nil {
+		b.config.limiter.start(b.config.clock)
+	}
 
-	b.state.startTime = b.config.now()
-	_ = b.render(b.state.startTime)
+	b.state.startTime = b.config.clock.Now().Add(-b.config.elapsed)
+	if b.config.initial != 0 {
+		_ = b.count(b.config.initial) // not a burst to sample the rate of
+		b.state.counterNumSinceLast = 0
+		b.state.offset = b.config.offset
+		b.updatePercent()
+		b.state.lastPercent = b.state.currentPercent
+	}
+	_ = b.render(b.config.clock.Now())
+
+	return &b, nil
+}
 
-	return &b
+// NewE constructs a new instance of ProgressBar with specified options,
+// returning an error instead of panicking if they're invalid.
+//
+// With max == -1 it creates a spinner.
+func NewE(max int64, options ...Option) (*ProgressBar, error) {
+	if max < -1 {
+		return nil, ErrNegativeMax
+	}
+	return newProgressBar(max, options)
 }
 
 // DefaultBytes creates a new ProgressBar for measuring bytes throughput
@@ -330,30 +599,42 @@ func New64(max int64, options ...Option) *ProgressBar {
 //
 // With maxBytes == -1 it creates a spinner.
 func DefaultBytes(maxBytes int64, description ...string) *ProgressBar {
-	return New64(maxBytes,
-		OptionDescription(strings.Join(description, " ")),
+	return New64(maxBytes, defaultBytesOptions(strings.Join(description, " "))...)
+}
+
+// defaultBytesOptions returns the options of DefaultBytes.
+func defaultBytesOptions(description string) []Option {
+	return []Option{
+		OptionDescription(description),
 		OptionWriter(os.Stderr),
 		OptionShowBytes(),
 		OptionWidth(10),
-		OptionThrottle(65*time.Millisecond),
+		OptionThrottle(65 * time.Millisecond),
 		OptionShowCount(),
 		OptionSpinnerStyle(14),
-		OptionFullWidth())
+		OptionFullWidth(),
+	}
 }
 
 // Default creates a new ProgressBar with some reasonable default options.
 //
 // With max == -1 it creates a spinner.
 func Default(max int64, description ...string) *ProgressBar {
-	return New64(max,
-		OptionDescription(strings.Join(description, " ")),
+	return New64(max, defaultOptions(strings.Join(description, " "))...)
+}
+
+// defaultOptions returns the options of Default.
+func defaultOptions(description string) []Option {
+	return []Option{
+		OptionDescription(description),
 		OptionWriter(os.Stderr),
 		OptionWidth(10),
-		OptionThrottle(65*time.Millisecond),
+		OptionThrottle(65 * time.Millisecond),
 		OptionShowCount(),
 		OptionShowIts(),
 		OptionSpinnerStyle(14),
-		OptionFullWidth())
+		OptionFullWidth(),
+	}
 }
 
 // String returns progress bar's current rendering.
@@ -361,13 +642,20 @@ func (p *ProgressBar) String() string {
 	p.Lock()
 	defer p.Unlock()
 
-	return p.state.rendered
+	return string(p.state.line)
 }
 
 // Reset resets progress bar to initial state.
 func (p *ProgressBar) Reset() {
 	p.Lock()
-	p.state = state{startTime: p.config.now()}
+	p.state = state{
+		startTime:    p.config.clock.Now(),
+		cursorHidden: p.state.cursorHidden,
+		sigwatch:     p.state.sigwatch,
+	}
+	p.takePending()
+	p.nextRender.Store(0)
+	p.updateHeadroom()
 	p.Unlock()
 }
 
@@ -376,8 +664,11 @@ func (p *ProgressBar) Finish() error {
 	p.Lock()
 	defer p.Unlock()
 
+	p.resume()
+
 	if !p.state.finished {
 		if !p.config.ignoreLength {
+			p.takePending()
 			p.state.currentNum, p.state.currentBytes = p.config.max, float64(p.config.max)
 		}
 		p.state.finished = true
@@ -389,10 +680,20 @@ func (p *ProgressBar) Finish() error {
 			}
 		}
 	}
+	p.state.closed = true
+	p.nextRender.Store(0)
 	if p.config.clearOnFinish {
-		return clearProgressBar(&p.config, &p.state)
+		clearProgressBar(&p.config, &p.state)
+	} else {
+		writeString(&p.config, &p.state, "\n")
 	}
-	return writeString(&p.config, "\n")
+	p.unwatchSignals()
+	p.showCursor()
+	err := flushFrame(&p.config, &p.state)
+	if m := p.config.multi; m != nil && p.config.clearOnFinish {
+		err = m.remove(p)
+	}
+	return err
 }
 
 // Stop stops progress bar at current state.
@@ -400,6 +701,32 @@ func (p *ProgressBar) Stop() error {
 	p.Lock()
 	defer p.Unlock()
 
+	return p.stop()
+}
+
+// Fail stops progress bar at current state, telling that it has failed.
+func (p *ProgressBar) Fail() error {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.state.closed {
+		return nil
+	}
+	p.state.failed = true
+	if p.state.finished {
+		// stop leaves the line of a full bar as it is
+		if err := p.render(p.now()); err != nil {
+			return err
+		}
+	}
+	return p.stop()
+}
+
+// stop stops progress bar at current state. this function
+// is not thread-safe, so it must be called with an acquired lock.
+func (p *ProgressBar) stop() error {
+	p.resume()
+
 	if !p.state.finished {
 		p.state.stopped = true
 
@@ -408,55 +735,176 @@ func (p *ProgressBar) Stop() error {
 			return err
 		}
 	}
-	return writeString(&p.config, "\n")
+	p.state.closed = true
+	p.nextRender.Store(0)
+	writeString(&p.config, &p.state, "\n")
+	p.unwatchSignals()
+	p.showCursor()
+	return flushFrame(&p.config, &p.state)
 }
 
-// Add adds specified delta to progress bar's current value.
-func (p *ProgressBar) Add(delta int) error {
+// Pause pauses progress bar, so that its elapsed time is frozen, and the time
+// until Resume is not taken into account in rate and remaining time estimates.
+func (p *ProgressBar) Pause() error {
 	p.Lock()
 	defer p.Unlock()
 
-	return p.add(int64(delta))
+	if !p.state.pausedAt.IsZero() || p.state.closed {
+		return nil
+	}
+	p.state.pausedAt = p.config.clock.Now()
+	return p.render(p.state.pausedAt)
+}
+
+// Resume resumes paused progress bar.
+func (p *ProgressBar) Resume() error {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.state.pausedAt.IsZero() {
+		return nil
+	}
+	p.resume()
+	return p.render(p.now())
+}
+
+// resume resumes the bar if it's paused, excluding the time it's been paused from
+// its timings. this function is not thread-safe, so it must be called with an
+// acquired lock.
+func (p *ProgressBar) resume() {
+	if p.state.pausedAt.IsZero() {
+		return
+	}
+	d := p.config.clock.Now().Sub(p.state.pausedAt)
+	p.state.startTime = p.state.startTime.Add(d)
+	if !p.state.counterTime.IsZero() {
+		p.state.counterTime = p.state.counterTime.Add(d)
+	}
+	p.state.pausedAt = time.Time{}
+}
+
+// now returns the current time as far as the bar is concerned, which
+// is frozen while it's paused. this function is not thread-safe,
+// so it must be called with an acquired lock.
+func (p *ProgressBar) now() time.Time {
+	if !p.state.pausedAt.IsZero() {
+		return p.state.pausedAt
+	}
+	return p.config.clock.Now()
+}
+
+// Add adds specified delta to progress bar's current value.
+//
+// Negative delta rolls the progress back, but not below zero, and isn't
+// taken into account in the rate. While the bar is throttled, positive deltas
+// that don't fill it up are merely counted, without acquiring the lock or rendering.
+func (p *ProgressBar) Add(delta int) error {
+	return p.Add64(int64(delta))
 }
 
 // Add64 adds specified delta to progress bar's current value.
+//
+// Negative delta rolls the progress back, but not below zero, and isn't
+// taken into account in the rate. While the bar is throttled, positive deltas
+// that don't fill it up are merely counted, without acquiring the lock or rendering.
 func (p *ProgressBar) Add64(delta int64) error {
+	if p.addPending(delta) {
+		return nil
+	}
+
 	p.Lock()
 	defer p.Unlock()
 
+	if p.state.closed {
+		return ErrFinished
+	}
 	return p.add(delta)
 }
 
 // Set sets progress bar's current value.
+//
+// Value less than the current one rolls the progress back, as with negative Add.
 func (p *ProgressBar) Set(value int) error {
-	p.Lock()
-	defer p.Unlock()
-
-	return p.add(int64(value) - int64(p.state.currentBytes))
+	return p.Set64(int64(value))
 }
 
 // Set64 sets progress bar's current value.
+//
+// Value less than the current one rolls the progress back, as with negative Add.
 func (p *ProgressBar) Set64(value int64) error {
 	p.Lock()
 	defer p.Unlock()
 
+	if p.state.closed {
+		return ErrFinished
+	}
+	p.takePending() // superseded by value
 	return p.add(value - int64(p.state.currentBytes))
 }
 
-func (p *ProgressBar) add(delta int64) error {
-	now := p.config.now()
+// addPending adds positive delta to pending increments if the bar is throttled
+// and isn't going to be full, and reports whether it did so. It's safe to call
+// without acquiring the lock, the increments are accounted for by the next add.
+func (p *ProgressBar) addPending(delta int64) bool {
+	if delta <= 0 || p.nextRender.Load() <= p.config.clock.Now().UnixNano() {
+		return false
+	}
+	for {
+		room := p.room.Load()
+		if delta >= room {
+			return false
+		}
+		if p.room.CompareAndSwap(room, room-delta) {
+			if !p.refreshing.Load() && p.refreshing.CompareAndSwap(false, true) {
+				go p.refresh()
+			}
+			return true
+		}
+	}
+}
 
-	p.state.currentNum += delta
-	if p.config.ignoreLength {
-		p.state.currentNum %= p.config.max
-	} else if p.state.currentNum > p.config.max {
-		return errors.New("current number exceeds max")
+// refresh renders the increments added by addPending once the bar is due to be
+// refreshed, so they're shown even if no more updates follow.
+func (p *ProgressBar) refresh() {
+	for {
+		if d := time.Duration(p.nextRender.Load() - p.config.clock.Now().UnixNano()); d > 0 {
+			t := p.config.clock.NewTicker(d)
+			<-t.C()
+			t.Stop()
+		}
+		p.Lock()
+		if p.nextRender.Load() <= p.config.clock.Now().UnixNano() {
+			p.refreshing.Store(false)
+			if !p.state.closed && p.room.Load() != p.headroom {
+				_ = p.add(0)
+			}
+			p.Unlock()
+			return
+		}
+		p.Unlock() // rendered meanwhile, so due later
 	}
+}
 
-	p.state.currentBytes += float64(delta)
+// takePending returns the increments added by addPending, leaving no room
+// for more until updateHeadroom, so they don't exceed max as the state
+// changes. this function is not thread-safe, so it must be called with
+// an acquired lock.
+func (p *ProgressBar) takePending() int64 {
+	pending := p.headroom - p.room.Swap(0)
+	p.headroom = 0
+	return pending
+}
 
-	if !p.config.totalRate {
-		p.state.counterNumSinceLast += delta
+func (p *ProgressBar) add(delta int64) error {
+	now := p.now()
+
+	err := p.count(p.takePending())
+	if err == nil {
+		err = p.count(delta)
+	}
+	p.updateHeadroom()
+	if err != nil {
+		return err
 	}
 
 	// make sure that the following is not happening too often
@@ -491,24 +939,101 @@ func (p *ProgressBar) add(delta int64) error {
 		}
 	}
 
-	percent := 0.0
-	if p.config.max > 0 {
-		percent = float64(p.state.currentNum) / float64(p.config.max)
-	}
-	p.state.currentSaucerSize = int(percent * float64(p.config.width))
-	p.state.currentPercent = int(percent * 100)
+	p.updatePercent()
 	updateBar := p.state.currentPercent != p.state.lastPercent && p.state.currentPercent > 0
 
 	p.state.lastPercent = p.state.currentPercent
 
-	// always update if show bytes/second or its/second
-	if updateBar || p.config.showCount || p.config.showIts || p.config.showBytes || delta == 0 {
+	// always update if show bytes/second or its/second, or rolled back
+	if updateBar || p.config.showCount || p.config.showIts || p.config.showBytes || delta <= 0 {
 		return p.render(now)
 	}
 
 	return nil
 }
 
+// count accounts for delta in progress bar's current value. this function
+// is not thread-safe, so it must be called with an acquired lock.
+func (p *ProgressBar) count(delta int64) error {
+	if !p.config.ignoreLength && delta > p.config.max-p.state.currentNum {
+		switch p.config.overflow {
+		case OverflowClamp:
+			delta = p.config.max - p.state.currentNum
+		case OverflowGrow:
+			p.config.max = p.state.currentNum + delta
+			p.config.maxHumanized, p.config.maxHumanizedSuffix = humanizeBytes(float64(p.config.max))
+		case OverflowUnknownLength:
+			p.config.ignoreLength = true
+			p.config.max = int64(len(spinners[p.config.spinnerType]))
+			p.config.predictTime = false
+			p.checkTrickyWidths()
+		default:
+			return ErrExceedsMax
+		}
+	}
+	if delta < 0 {
+		p.rollback(-delta)
+		return nil
+	}
+	if delta > 0 {
+		p.state.rolledBack = false
+	}
+
+	p.state.currentNum += delta
+	if p.config.ignoreLength {
+		p.state.currentNum %= p.config.max
+	}
+
+	p.state.currentBytes += float64(delta)
+
+	if !p.config.totalRate {
+		p.state.counterNumSinceLast += delta
+	}
+	return nil
+}
+
+// rollback decreases progress bar's current value by n, but not below zero.
+// What's rolled back is discounted from the progress sampled for the rate next,
+// so that redoing it doesn't count. this function is not thread-safe, so it must
+// be called with an acquired lock.
+func (p *ProgressBar) rollback(n int64) {
+	n = min(n, int64(p.state.currentBytes))
+	p.state.currentBytes -= float64(n)
+	if p.config.ignoreLength {
+		p.state.currentNum = int64(p.state.currentBytes) % p.config.max
+	} else {
+		p.state.currentNum = max(p.state.currentNum-n, 0)
+	}
+	if !p.config.totalRate {
+		p.state.counterNumSinceLast -= n
+	}
+	p.state.offset = min(p.state.offset, int64(p.state.currentBytes))
+	p.state.rolledBack = n > 0
+}
+
+// updatePercent updates the percentage and the size of the saucer. this
+// function is not thread-safe, so it must be called with an acquired lock.
+func (p *ProgressBar) updatePercent() {
+	percent := 0.0
+	if p.config.max > 0 {
+		percent = float64(p.state.currentNum) / float64(p.config.max)
+	}
+	p.state.currentSaucerSize = int(percent * float64(p.config.width))
+	p.state.currentPercent = int(percent * 100)
+}
+
+// updateHeadroom publishes how much can be added before the bar is full,
+// keeping the increments pending, if any. this function is not thread-safe,
+// so it must be called with an acquired lock.
+func (p *ProgressBar) updateHeadroom() {
+	headroom := int64(math.MaxInt64)
+	if !p.config.ignoreLength {
+		headroom = max(p.config.max-p.state.currentNum, 0)
+	}
+	p.room.Add(headroom - p.headroom)
+	p.headroom = headroom
+}
+
 func (p *ProgressBar) addRate(rate float64) {
 	if len(p.state.counterLastTenRates) < 10 {
 		p.state.counterLastTenRates = append(p.state.counterLastTenRates, rate)
@@ -526,7 +1051,9 @@ func (p *ProgressBar) Clear() error {
 	p.Lock()
 	defer p.Unlock()
 
-	return clearProgressBar(&p.config, &p.state)
+	clearProgressBar(&p.config, &p.state)
+	p.showCursor()
+	return flushFrame(&p.config, &p.state)
 }
 
 // SetDescription changes progress bar's description label.
@@ -537,8 +1064,9 @@ func (p *ProgressBar) SetDescription(s string) {
 	p.config.description = s
 
 	p.checkTrickyWidths()
+	p.updateSegments()
 
-	_ = p.render(p.config.now())
+	_ = p.render(p.now())
 }
 
 // Max returns progress bar's maximum value.
@@ -590,8 +1118,12 @@ func (p *ProgressBar) SetMax64(max int64) error {
 }
 
 func (p *ProgressBar) setMax(max int64) error {
-	if p.config.max < 0 {
-		return errors.New("max must be nonnegative")
+	if max < 0 {
+		return ErrNegativeMax
+	}
+	// pending increments are accounted for within the max they're added under
+	if err := p.count(p.takePending()); err != nil {
+		return err
 	}
 
 	p.config.max = max
@@ -605,31 +1137,75 @@ func (p *ProgressBar) setMax(max int64) error {
 // rendered line width. this function is not thread-safe,
 // so it must be called with an acquired lock.
 func (p *ProgressBar) render(now time.Time) error {
+	if p.config.hideCursor && !p.state.cursorHidden && p.config.visible {
+		writeString(&p.config, &p.state, "\033[?25l")
+		p.state.cursorHidden = true
+		p.watchSignals()
+	}
+	if p.config.handleInterrupt && !p.state.finished {
+		p.watchSignals()
+	}
+
 	if !p.config.useANSICodes {
 		// first, clear the existing progress bar
-		if err := clearProgressBar(&p.config, &p.state); err != nil {
-			return err
-		}
+		clearProgressBar(&p.config, &p.state)
 	}
 
 	// check if the progress bar is finished
 	if !p.state.finished && (p.state.currentNum >= p.config.max || p.state.stopped) {
 		p.state.finished = true
+		if !p.state.cursorHidden {
+			p.unwatchSignals() // otherwise still needed to show the cursor
+		}
 	}
 
 	// then, re-render the current progress bar
-	w, err := renderProgressBar(&p.config, &p.state, now)
-	if err != nil {
-		return err
-	}
+	w := renderProgressBar(&p.config, &p.state, now)
 
 	if w > p.state.maxLineWidth {
 		p.state.maxLineWidth = w
 	}
 
 	p.state.lastShown = now
+	if p.config.throttleInterval > 0 && !p.state.closed {
+		p.nextRender.Store(now.Add(p.config.throttleInterval).UnixNano())
+	}
 
-	return nil
+	err := flushFrame(&p.config, &p.state)
+	if m := p.config.multi; m != nil && p.config.visible {
+		err = m.update(p, p.state.line)
+	}
+	return err
+}
+
+// showCursor shows the cursor if it has been hidden. this function
+// is not thread-safe, so it must be called with an acquired lock.
+func (p *ProgressBar) showCursor() {
+	if !p.state.cursorHidden {
+		return
+	}
+	if !p.config.handleInterrupt {
+		p.unwatchSignals()
+	}
+	p.state.cursorHidden = false
+	writeString(&p.config, &p.state, "\033[?25h")
+}
+
+// printAbove prints b, which is expected to end with a newline, above the progress bar
+// and then re-renders the bar. this function is not thread-safe, so it must be called
+// with an acquired lock.
+func (p *ProgressBar) printAbove(b []byte) error {
+	if m := p.config.multi; m != nil {
+		return m.printAbove(b) // the other bars are displayed there too
+	}
+	if p.state.closed || !p.config.visible {
+		_, err := p.config.writer.Write(b)
+		return err
+	}
+	clearProgressBar(&p.config, &p.state)
+	p.state.frame = append(p.state.frame, b...)
+	p.state.maxLineWidth = 0 // the bar is on a fresh line now
+	return p.render(p.now())
 }
 
 // checkTrickyWidths checks if any progress bar element's width in screen characters
@@ -661,129 +1237,246 @@ func (p *ProgressBar) State() State {
 	p.Lock()
 	defer p.Unlock()
 
+	// max doesn't change while there are increments pending, so they can't exceed it
+	_ = p.count(p.takePending())
+	p.updateHeadroom()
+
+	c, now := &p.config, p.now()
 	s := State{
+		Max:          c.max,
+		Current:      p.state.currentNum,
 		CurrentBytes: p.state.currentBytes,
-		SecondsSince: p.config.now().Sub(p.state.startTime).Seconds(),
+		SecondsSince: now.Sub(p.state.startTime).Seconds(),
+		Rate:         currentRate(c, &p.state, now),
+		OffsetBytes:  float64(p.state.offset),
+		Description:  c.description,
+		StartTime:    p.state.startTime,
+		Finished:     p.state.finished,
+		Stopped:      p.state.stopped,
+		Failed:       p.state.failed,
+		Paused:       !p.state.pausedAt.IsZero(),
 	}
-	if !p.config.ignoreLength && s.CurrentBytes > 0 {
+	if c.ignoreLength {
+		s.Max, s.Current = -1, int64(p.state.currentBytes)
+	} else if s.CurrentBytes > 0 {
 		s.CurrentPercent = 0.0
-		if p.config.max > 0 {
-			s.CurrentPercent = s.CurrentBytes / float64(p.config.max)
+		if c.max > 0 {
+			s.CurrentPercent = s.CurrentBytes / float64(c.max)
+		}
+		if est, ok := remainingTime(c, &p.state, s.Rate); ok {
+			s.SecondsLeft = est.Round(time.Second).Seconds() // as displayed
 		}
-		s.SecondsLeft = s.SecondsSince / s.CurrentBytes * (float64(p.config.max) - s.CurrentBytes)
 	}
 	return s
 }
 
-// Regex matching ANSI escape codes.
-var ansiRegex = regexp.MustCompile(`\033(?:[@-Z\\-_]|\[[0-?]*[ -/]*[@-~])`)
+// displayWidth returns the width of b in screen characters,
+// not counting any ANSI escape sequences in it.
+func displayWidth(c *config, b []byte) int {
+	width := 0
+	for len(b) > 0 {
+		if b[0] == '\033' {
+			if n := escapeLen(b); n > 0 {
+				b = b[n:]
+				continue
+			}
+		}
+		// measure everything up to the next escape sequence
+		n := bytes.IndexByte(b[1:], '\033') + 1
+		if n == 0 {
+			n = len(b)
+		}
+		if c.trickyWidths {
+			for seg, state := b[:n], -1; len(seg) > 0; {
+				var w int
+				_, seg, w, state = uniseg.FirstGraphemeCluster(seg, state)
+				width += w
+			}
+		} else {
+			for seg := b[:n]; len(seg) > 0; width++ {
+				_, size := utf8.DecodeRune(seg)
+				seg = seg[size:]
+			}
+		}
+		b = b[n:]
+	}
+	return width
+}
+
+// escapeLen returns the length of the ANSI escape sequence b starts with, or zero.
+func escapeLen(b []byte) int {
+	if len(b) < 2 || b[0] != '\033' {
+		return 0
+	}
+	if c := b[1]; c >= '@' && c <= '_' && c != '[' {
+		return 2
+	} else if c != '[' {
+		return 0
+	}
+	i := 2
+	for i < len(b) && b[i] >= '0' && b[i] <= '?' { // parameter bytes
+		i++
+	}
+	for i < len(b) && b[i] >= ' ' && b[i] <= '/' { // intermediate bytes
+		i++
+	}
+	if i < len(b) && b[i] >= '@' && b[i] <= '~' { // final byte
+		return i + 1
+	}
+	return 0
+}
 
 func getStringWidth(c *config, str string) int {
 	if c.colorCodes {
 		// convert any color codes in the progress bar into respective ANSI codes
-		str = colorstring.Color(str)
+		str = colorize(c, str)
 	}
-	if c.colorCodes || c.useANSICodes {
-		// ANSI codes for colors do not take up space in the console output,
-		// so they do not count towards the output string width
-		str = ansiRegex.ReplaceAllString(str, "")
+	return displayWidth(c, []byte(str))
+}
+
+// colorize converts color codes in str into respective ANSI codes
+// or removes them if colors are to be stripped.
+func colorize(c *config, str string) string {
+	if c.stripColors {
+		return (&colorstring.Colorize{Colors: colorstring.DefaultColors, Disable: true, Reset: true}).Color(str)
 	}
-	if c.trickyWidths {
-		return uniseg.StringWidth(str)
+	return colorstring.Color(str)
+}
+
+// segments are the parts of progress bar rendering that don't change between frames.
+type segments struct {
+	description      string // styled, if styles are applied
+	descriptionWidth int
+	barStart         string // styled, if styles are applied
+	barEnd           string // styled, if styles are applied
+
+	// ANSI escape sequences setting up styles, if styles are applied
+	percentSGR string
+	paddingSGR string
+	rateSGR    string
+}
+
+// updateSegments precomputes the parts of progress bar rendering that don't change
+// between frames. this function is not thread-safe, so it must be called with an
+// acquired lock.
+func (p *ProgressBar) updateSegments() {
+	c := &p.config
+	c.segments = segments{
+		description:      c.description,
+		descriptionWidth: getStringWidth(c, c.description),
+		barStart:         c.theme.BarStart,
+		barEnd:           c.theme.BarEnd,
+	}
+	if c.styled {
+		c.segments.description = paint(c.styles.Description, c.description)
+		c.segments.barStart = paint(c.styles.BarStart, c.theme.BarStart)
+		c.segments.barEnd = paint(c.styles.BarEnd, c.theme.BarEnd)
+		c.segments.percentSGR = sgr(c.styles.Percent)
+		c.segments.paddingSGR = sgr(c.styles.SaucerPadding)
+		c.segments.rateSGR = sgr(c.styles.Rate)
 	}
-	return utf8.RuneCountInString(str)
 }
 
-func renderProgressBar(c *config, s *state, now time.Time) (int, error) {
-	var sb strings.Builder
+func renderProgressBar(c *config, s *state, now time.Time) int {
+	stats := s.stats[:0]
 
 	// show iteration count in "current/total" iterations format
 	if c.showCount {
-		sb.WriteString("(")
+		stats = append(stats, '(')
 		if !c.ignoreLength {
 			if c.showBytes {
-				currentHumanize, currentSuffix := "0", ""
 				if s.currentBytes > 0 {
-					currentHumanize, currentSuffix = humanizeBytes(s.currentBytes)
-				}
-				if currentSuffix == c.maxHumanizedSuffix || currentSuffix == "" {
-					sb.WriteString(fmt.Sprintf("%s/%s %s",
-						currentHumanize, c.maxHumanized, c.maxHumanizedSuffix))
+					var suffix string
+					stats, suffix = appendHumanizedBytes(stats, s.currentBytes)
+					if suffix != c.maxHumanizedSuffix {
+						stats = append(append(stats, ' '), suffix...)
+					}
 				} else {
-					sb.WriteString(fmt.Sprintf("%s %s/%s %s",
-						currentHumanize, currentSuffix, c.maxHumanized, c.maxHumanizedSuffix))
+					stats = append(stats, '0')
 				}
+				stats = append(append(stats, '/'), c.maxHumanized...)
+				stats = append(append(stats, ' '), c.maxHumanizedSuffix...)
 			} else {
-				sb.WriteString(fmt.Sprintf("%.0f/%d", s.currentBytes, c.max))
+				stats = strconv.AppendFloat(stats, s.currentBytes, 'f', 0, 64)
+				stats = strconv.AppendInt(append(stats, '/'), c.max, 10)
 			}
 		} else {
 			if c.showBytes {
-				currentHumanize, currentSuffix := humanizeBytes(s.currentBytes)
-				sb.WriteString(fmt.Sprintf("%s %s", currentHumanize, currentSuffix))
+				var suffix string
+				stats, suffix = appendHumanizedBytes(stats, s.currentBytes)
+				stats = append(append(stats, ' '), suffix...)
 			} else if !s.finished || s.stopped {
-				sb.WriteString(fmt.Sprintf("%.0f/%s", s.currentBytes, "?"))
+				stats = strconv.AppendFloat(stats, s.currentBytes, 'f', 0, 64)
+				stats = append(stats, "/?"...)
 			} else {
-				sb.WriteString(fmt.Sprintf("%.0f/%.0f", s.currentBytes, s.currentBytes))
+				stats = strconv.AppendFloat(stats, s.currentBytes, 'f', 0, 64)
+				stats = strconv.AppendFloat(append(stats, '/'), s.currentBytes, 'f', 0, 64)
 			}
 		}
 	}
 
-	rate := 0.0
-	if !s.finished && !c.totalRate && len(s.counterLastTenRates) > 0 {
-		// display recent rolling average rate
-		rate = average(s.counterLastTenRates)
-	} else if t := now.Sub(s.startTime); t > 0 {
-		// if no average samples, or if finished, or total rate option is set
-		// then display total rate
-		rate = s.currentBytes / t.Seconds()
-	}
+	rate := currentRate(c, s, now)
 
 	// format rate as units of bytes per second
 	if c.showBytes && rate > 0 && !math.IsInf(rate, 1) {
-		if sb.Len() == 0 {
-			sb.WriteString("(")
+		if len(stats) == 0 {
+			stats = append(stats, '(')
+		} else {
+			stats = append(stats, ", "...)
+		}
+		var suffix string
+		stats, suffix = appendHumanizedBytes(stats, rate)
+		stats = append(append(append(stats, ' '), suffix...), "/s"...)
+	}
+	if c.showBytes && c.limiter != nil {
+		if len(stats) == 0 {
+			stats = append(stats, '(')
 		} else {
-			sb.WriteString(", ")
+			stats = append(stats, ", "...)
 		}
-		currentHumanize, currentSuffix := humanizeBytes(rate)
-		sb.WriteString(fmt.Sprintf("%s %s/s", currentHumanize, currentSuffix))
+		var suffix string
+		stats, suffix = appendHumanizedBytes(append(stats, "limit "...), c.limiter.rate)
+		stats = append(append(append(stats, ' '), suffix...), "/s"...)
 	}
 
 	// format rate as iterations per second/minute/hour
 	if c.showIts {
-		if sb.Len() == 0 {
-			sb.WriteString("(")
+		if len(stats) == 0 {
+			stats = append(stats, '(')
 		} else {
-			sb.WriteString(", ")
+			stats = append(stats, ", "...)
 		}
 		if rate > 1.618 || rate == 0 {
-			sb.WriteString(fmt.Sprintf("%0.0f %s/s", math.Round(rate), c.iterationString))
+			stats = strconv.AppendFloat(stats, math.Round(rate), 'f', 0, 64)
+			stats = append(append(append(stats, ' '), c.iterationString...), "/s"...)
 		} else if 60*rate > 1.618 {
-			sb.WriteString(fmt.Sprintf("%0.0f %s/min", math.Round(60*rate), c.iterationString))
+			stats = strconv.AppendFloat(stats, math.Round(60*rate), 'f', 0, 64)
+			stats = append(append(append(stats, ' '), c.iterationString...), "/min"...)
 		} else {
-			sb.WriteString(fmt.Sprintf("%0.0f %s/h", math.Round(3600*rate), c.iterationString))
+			stats = strconv.AppendFloat(stats, math.Round(3600*rate), 'f', 0, 64)
+			stats = append(append(append(stats, ' '), c.iterationString...), "/h"...)
 		}
 	}
-	if sb.Len() > 0 {
-		sb.WriteString(")")
+	if len(stats) > 0 {
+		stats = append(stats, ')')
 	}
+	s.stats = stats
 
-	leftBrac, rightBrac, saucer, saucerHead := "", "", "", ""
-
-	// show time prediction in "current/total" seconds format
+	// show time prediction in "current/total" seconds format,
+	// both are kept in a single buffer, the estimate goes first
+	brackets, split := s.brackets[:0], 0
 	switch {
 	case c.predictTime:
-		if c.max >= s.currentNum && s.currentNum > 0 {
-			var est time.Duration
-			if rate > 0 {
-				est = time.Duration(float64(c.max-s.currentNum) / rate * float64(time.Second))
-			}
-			rightBrac = est.Round(time.Second).String()
+		if est, ok := remainingTime(c, s, rate); ok {
+			brackets = appendDuration(brackets, est)
+			split = len(brackets)
 		}
 		fallthrough
 	case c.elapsedTime:
-		leftBrac = now.Sub(s.startTime).Round(time.Second).String()
+		brackets = appendDuration(brackets, now.Sub(s.startTime))
 	}
+	s.brackets = brackets
+	leftBrac, rightBrac := brackets[split:], brackets[:split]
 
 	if c.fullWidth && !c.ignoreLength {
 		width, err := termWidth(c.writer)
@@ -793,141 +1486,361 @@ func renderProgressBar(c *config, s *state, now time.Time) (int, error) {
 
 		amend := 1 // an extra space at eol
 		switch {
-		case leftBrac != "" && rightBrac != "":
+		case len(leftBrac) > 0 && len(rightBrac) > 0:
 			amend += 4 // space, square brackets and colon
-		case leftBrac != "" && rightBrac == "":
+		case len(leftBrac) > 0 && len(rightBrac) == 0:
 			amend += 3 // space and square brackets
-		case leftBrac == "" && rightBrac != "":
+		case len(leftBrac) == 0 && len(rightBrac) > 0:
 			amend += 3 // space and square brackets
 		}
-		if sb.Len() > 0 {
+		if len(stats) > 0 {
 			amend += 1 // another space
 		}
 		if c.description != "" {
 			amend += 1 // another space
 		}
+		if word := status(c, s); word != "" {
+			amend += 1 + len(word) // space and the status
+		}
 
-		c.width = width - getStringWidth(c, c.description) - 8 - amend - sb.Len() - len(leftBrac) - len(rightBrac)
+		c.width = width - c.segments.descriptionWidth - 8 - amend - len(stats) - len(leftBrac) - len(rightBrac)
 		s.currentSaucerSize = int(float64(s.currentPercent) / 100 * float64(c.width))
 	}
 
-	if s.currentSaucerSize > 0 {
-		if c.ignoreLength {
-			saucer = strings.Repeat(c.theme.SaucerPadding, s.currentSaucerSize-1)
-		} else {
-			saucer = strings.Repeat(c.theme.Saucer, s.currentSaucerSize-1)
-		}
-		if c.theme.SaucerHead == "" || s.currentSaucerSize == c.width {
-			// use the saucer for the saucer head if it hasn't been set
-			// to preserve backwards compatibility
-			saucerHead = c.theme.Saucer
-		} else {
-			saucerHead = c.theme.SaucerHead
-		}
-	}
-
 	/*
 		Progress Bar format
 		Description % |------        |  (KB/s) (iteration count) (iteration rate) (predict time)
 	*/
 
-	repeatAmount := max(c.width-s.currentSaucerSize, 0)
-
-	str := ""
+	line := s.line[:0]
 
 	if c.ignoreLength {
 		if !s.finished {
 			dt, st := now.Sub(s.startTime).Seconds(), c.spinnerType
-			str = " " +
-				spinners[st][int(math.Mod(10*dt, float64(len(spinners[st]))))] +
-				sp(" ", c.description != "") +
-				c.description +
-				sp(" ", sb.Len() > 0) +
-				sb.String() +
-				sp(" [", c.elapsedTime) +
-				sp(leftBrac, c.elapsedTime) +
-				sp("]", c.elapsedTime) + " "
-		} else {
-			str = sp("100%", !s.stopped) +
-				sp(" ", c.description != "") +
-				c.description +
-				sp(" ", sb.Len() > 0) +
-				sb.String() +
-				sp(" [", c.elapsedTime) +
-				sp(leftBrac, c.elapsedTime) +
-				sp("]", c.elapsedTime) + " "
+			line = append(line, ' ')
+			line = append(line, spinners[st][int(math.Mod(10*dt, float64(len(spinners[st]))))]...)
+		} else if !s.stopped {
+			line = appendStyled(line, c.segments.percentSGR, "100%")
+		}
+		if c.description != "" {
+			line = append(append(line, ' '), c.segments.description...)
+		}
+		if len(stats) > 0 {
+			line = appendStyled(append(line, ' '), c.segments.rateSGR, stats)
+		}
+		if word := status(c, s); word != "" {
+			line = append(append(line, ' '), word...)
+		}
+		if c.elapsedTime {
+			line = append(append(append(line, " ["...), leftBrac...), ']')
 		}
-	} else if rightBrac == "" || s.finished {
-		str = "" +
-			c.description +
-			sp(" ", c.description != "") +
-			fmt.Sprintf("%3d%% ", s.currentPercent) +
-			c.theme.BarStart +
-			saucer +
-			saucerHead +
-			strings.Repeat(c.theme.SaucerPadding, repeatAmount) +
-			c.theme.BarEnd +
-			sp(" ", sb.Len() > 0) +
-			sb.String() +
-			sp(" [", c.elapsedTime || c.predictTime) +
-			sp(leftBrac, c.elapsedTime || c.predictTime) +
-			sp("]", c.elapsedTime || c.predictTime) + " "
 	} else {
-		str = "" +
-			c.description +
-			sp(" ", c.description != "") +
-			fmt.Sprintf("%3d%% ", s.currentPercent) +
-			c.theme.BarStart +
-			saucer +
-			saucerHead +
-			strings.Repeat(c.theme.SaucerPadding, repeatAmount) +
-			c.theme.BarEnd +
-			sp(" ", sb.Len() > 0) +
-			sb.String() +
-			" [" + leftBrac + ":" + rightBrac + "] "
+		line = append(line, c.segments.description...)
+		if c.description != "" {
+			line = append(line, ' ')
+		}
+		if c.segments.percentSGR != "" {
+			line = append(line, c.segments.percentSGR...)
+		}
+		line = appendPadded(line, s.currentPercent, 3)
+		line = append(line, '%')
+		if c.segments.percentSGR != "" {
+			line = append(line, sgrReset...)
+		}
+		line = append(append(line, ' '), c.segments.barStart...)
+		line = appendSaucer(line, c, s)
+		line = appendRepeated(line, c.segments.paddingSGR, c.theme.SaucerPadding, c.width-s.currentSaucerSize)
+		line = append(line, c.segments.barEnd...)
+		if len(stats) > 0 {
+			line = appendStyled(append(line, ' '), c.segments.rateSGR, stats)
+		}
+		if word := status(c, s); word != "" {
+			line = append(append(line, ' '), word...)
+		}
+		if len(rightBrac) == 0 || s.finished {
+			if c.elapsedTime || c.predictTime {
+				line = append(append(append(line, " ["...), leftBrac...), ']')
+			}
+		} else {
+			line = append(append(line, " ["...), leftBrac...)
+			line = append(append(append(line, ':'), rightBrac...), ']')
+		}
 	}
+	line = append(line, ' ')
+
 	if c.colorCodes {
 		// convert any color codes in the progress bar into the respective ANSI codes
-		str = colorstring.Color(str)
+		line = append(line[:0], colorize(c, string(line))...)
 	}
 
-	s.rendered = str
+	s.line = line
 
-	if c.useANSICodes {
+	switch {
+	case c.diffUpdates:
+		writeDiff(c, s, line)
+	case c.useANSICodes:
 		// append the "clear rest of line" ANSI escape sequence
-		str = "\r" + str + "\033[0K"
+		writeString(c, s, "\r")
+		writeBytes(c, s, line)
+		writeString(c, s, "\033[0K")
+	default:
+		writeBytes(c, s, line)
 	}
+	return displayWidth(c, line)
+}
 
-	return getStringWidth(c, str), writeString(c, str)
+// cell is a character on the screen, which is a grapheme cluster that may be wide.
+type cell struct {
+	end   int // offset of the end of the cell in its line
+	width int
 }
 
-func clearProgressBar(c *config, s *state) error {
+// appendCells appends cells of line to cc.
+func appendCells(cc []cell, c *config, line []byte) []cell {
+	for end := 0; end < len(line); {
+		width := 1
+		if c.trickyWidths {
+			var cluster []byte
+			cluster, _, width, _ = uniseg.FirstGraphemeCluster(line[end:], -1)
+			end += len(cluster)
+		} else {
+			_, size := utf8.DecodeRune(line[end:])
+			end += size
+		}
+		cc = append(cc, cell{end: end, width: width})
+	}
+	return cc
+}
+
+// writeDiff writes the cells of line that differ from the line written previously,
+// moving the cursor forward over the unchanged ones. It writes the whole line if
+// the previous one is not known, or any of the cells have changed their width.
+func writeDiff(c *config, s *state, line []byte) {
+	s.cells = appendCells(s.cells[:0], c, line)
+	full := !s.diffable || len(s.cells) != len(s.writtenCells)
+	for i := 0; !full && i < len(s.cells); i++ {
+		full = s.cells[i].width != s.writtenCells[i].width
+	}
+
+	if full {
+		writeString(c, s, "\r")
+		writeBytes(c, s, line)
+		writeString(c, s, "\033[0K")
+	} else {
+		moved, skip := false, 0
+		for i, start, wstart := 0, 0, 0; i < len(s.cells); i++ {
+			cur, prev := line[start:s.cells[i].end], s.written[wstart:s.writtenCells[i].end]
+			start, wstart = s.cells[i].end, s.writtenCells[i].end
+			if bytes.Equal(cur, prev) {
+				skip += s.cells[i].width
+				continue
+			}
+			if !moved {
+				writeString(c, s, "\r")
+				moved = true
+			}
+			if skip > 0 {
+				// move the cursor forward over unchanged cells
+				if c.visible {
+					s.frame = strconv.AppendInt(append(s.frame, "\033["...), int64(skip), 10)
+					s.frame = append(s.frame, 'C')
+				}
+				skip = 0
+			}
+			writeBytes(c, s, cur)
+		}
+	}
+
+	s.written = append(s.written[:0], line...)
+	s.cells, s.writtenCells = s.writtenCells, s.cells
+	s.diffable = bytes.IndexByte(line, '\033') < 0 // escape sequences make cells stateful
+}
+
+// appendSaucer appends the filled part of the bar to b.
+func appendSaucer(b []byte, c *config, s *state) []byte {
+	if s.currentSaucerSize <= 0 {
+		return b
+	}
+	saucer, head := c.theme.Saucer, c.theme.SaucerHead
+	if c.ignoreLength {
+		saucer = c.theme.SaucerPadding
+	}
+	if head == "" || s.currentSaucerSize == c.width {
+		// use the saucer for the saucer head if it hasn't been set
+		// to preserve backwards compatibility
+		head = c.theme.Saucer
+	}
+	if !c.styled {
+		b = appendRepeated(b, "", saucer, s.currentSaucerSize-1)
+		return append(b, head...)
+	}
+
+	saucerStyle, headStyle := c.styles.Saucer, c.styles.SaucerHead
+	if head == c.theme.Saucer {
+		headStyle = saucerStyle
+	}
+	if len(c.styles.Gradient) > 0 {
+		saucerStyle.Fg = gradient(c.styles.Gradient, s.currentPercent)
+		if headStyle.Fg == (Color{}) {
+			headStyle.Fg = saucerStyle.Fg
+		}
+	}
+	if headStyle == saucerStyle {
+		if saucerStyle != (Style{}) {
+			b = saucerStyle.appendSGR(b)
+		}
+		b = append(appendRepeated(b, "", saucer, s.currentSaucerSize-1), head...)
+		if saucerStyle != (Style{}) {
+			b = append(b, sgrReset...)
+		}
+		return b
+	}
+	if saucerStyle != (Style{}) && s.currentSaucerSize > 1 {
+		b = appendRepeated(saucerStyle.appendSGR(b), "", saucer, s.currentSaucerSize-1)
+		b = append(b, sgrReset...)
+	} else {
+		b = appendRepeated(b, "", saucer, s.currentSaucerSize-1)
+	}
+	if headStyle != (Style{}) {
+		return append(append(headStyle.appendSGR(b), head...), sgrReset...)
+	}
+	return append(b, head...)
+}
+
+// appendRepeated appends n copies of str to b, styled with the sgr sequence if it's not empty.
+func appendRepeated(b []byte, sgr, str string, n int) []byte {
+	if n <= 0 || str == "" {
+		return b
+	}
+	if sgr != "" {
+		b = append(b, sgr...)
+	}
+	for range n {
+		b = append(b, str...)
+	}
+	if sgr != "" {
+		b = append(b, sgrReset...)
+	}
+	return b
+}
+
+// appendStyled appends str to b, styled with the sgr sequence if it's not empty.
+func appendStyled[T string | []byte](b []byte, sgr string, str T) []byte {
+	if sgr == "" || len(str) == 0 {
+		return append(b, str...)
+	}
+	return append(append(append(b, sgr...), str...), sgrReset...)
+}
+
+// appendPadded appends n to b, padded with spaces on the left to the given width.
+func appendPadded(b []byte, n, width int) []byte {
+	var digits [20]byte
+	d := strconv.AppendInt(digits[:0], int64(n), 10)
+	for i := len(d); i < width; i++ {
+		b = append(b, ' ')
+	}
+	return append(b, d...)
+}
+
+// appendDuration appends d rounded to seconds to b, formatted as time.Duration.String does.
+func appendDuration(b []byte, d time.Duration) []byte {
+	secs := int64(d.Round(time.Second) / time.Second)
+	if secs < 0 {
+		b, secs = append(b, '-'), -secs
+	}
+	h, m := secs/3600, secs/60%60
+	if h > 0 {
+		b = append(strconv.AppendInt(b, h, 10), 'h')
+	}
+	if h > 0 || m > 0 {
+		b = append(strconv.AppendInt(b, m, 10), 'm')
+	}
+	return append(strconv.AppendInt(b, secs%60, 10), 's')
+}
+
+func clearProgressBar(c *config, s *state) {
 	if s.maxLineWidth == 0 {
-		return nil
+		return
 	}
+	s.diffable = false
+
 	if c.useANSICodes {
 		// write the "clear current line" ANSI escape sequence
-		return writeString(c, "\033[2K\r")
+		writeString(c, s, "\033[2K\r")
+		return
 	}
 	if runtime.GOOS == "windows" {
-		return writeString(c, "\r")
+		writeString(c, s, "\r")
+		return
 	}
 	// overwrite the bar with spaces and return back to the beginning of the line
-	return writeString(c, "\r"+strings.Repeat(" ", s.maxLineWidth)+"\r")
+	if c.visible {
+		s.frame = append(s.frame, '\r')
+		for range s.maxLineWidth {
+			s.frame = append(s.frame, ' ')
+		}
+		s.frame = append(s.frame, '\r')
+	}
+}
+
+// writeString adds str to the frame to be written out by flushFrame.
+func writeString(c *config, s *state, str string) {
+	if c.visible {
+		s.frame = append(s.frame, str...)
+	}
+}
+
+// writeBytes adds b to the frame to be written out by flushFrame.
+func writeBytes(c *config, s *state, b []byte) {
+	if c.visible {
+		s.frame = append(s.frame, b...)
+	}
 }
 
-func writeString(c *config, str string) error {
-	if !c.visible {
+// flushFrame writes out the frame in a single write and flushes the writer.
+func flushFrame(c *config, s *state) error {
+	if len(s.frame) == 0 {
 		return nil
 	}
-	if _, err := io.WriteString(c.writer, str); err != nil {
-		return err
+	if c.multi != nil {
+		// the container displays the bar instead
+		s.frame = s.frame[:0]
+		return nil
 	}
-	if f, ok := c.writer.(*os.File); ok {
-		// ignore any errors in Sync(), as stdout
-		// can't be synced on some operating systems
-		// like Debian 9 (Stretch)
-		_ = f.Sync()
+	_, err := c.writer.Write(s.frame)
+	s.frame = s.frame[:0]
+	if err != nil {
+		return fmt.Errorf("writing progress bar: %w", err)
+	}
+	if c.flush != nil {
+		if err := c.flush(c.writer); err != nil {
+			return fmt.Errorf("flushing progress bar: %w", err)
+		}
+	}
+	return nil
+}
+
+// defaultFlush returns the function flushing w after each frame, if any is needed.
+func defaultFlush(w io.Writer) func(w io.Writer) error {
+	switch f := w.(type) {
+	case interface{ Flush() error }:
+		return func(io.Writer) error {
+			return f.Flush()
+		}
+	case *os.File:
+		fi, err := f.Stat()
+		if err == nil && (fi.Mode().IsRegular() || fi.Mode()&os.ModeCharDevice != 0) {
+			// writes to terminals are not buffered, and there's no
+			// point in committing progress bars to disk right away
+			return nil
+		}
+		return func(io.Writer) error {
+			// ignore any errors in Sync(), as stdout
+			// can't be synced on some operating systems
+			// like Debian 9 (Stretch)
+			_ = f.Sync()
+			return nil
+		}
 	}
 	return nil
 }
@@ -936,27 +1849,64 @@ func writeString(c *config, str string) error {
 type Reader struct {
 	r   io.Reader
 	bar *ProgressBar
+
+	hashes    []hash.Hash // the data read is written to
+	checksums [][]byte    // expected of the hashes, nil if none
+	eof       bool
 }
 
 // NewReader creates a new Reader with given io.Reader and progress bar.
-func NewReader(r io.Reader, bar *ProgressBar) Reader {
-	return Reader{
+func NewReader(r io.Reader, bar *ProgressBar, options ...ReaderOption) Reader {
+	reader := Reader{
 		r:   r,
 		bar: bar,
 	}
+	for _, o := range options {
+		o(&reader)
+	}
+	return reader
 }
 
 // Read reads buffer p and adds the number of bytes read to the progress bar.
+//
+// With OptionRateLimit, it reads at most the burst of bytes at once, and
+// waits until the bytes read are within the limit before returning.
 func (r *Reader) Read(p []byte) (n int, err error) {
+	l := r.bar.config.limiter
+	if l != nil && len(p) > l.burst {
+		p = p[:l.burst]
+	}
 	n, err = r.r.Read(p)
-	if err == nil {
+	if l != nil && n > 0 {
+		l.wait(n)
+	}
+	if n > 0 {
+		// the bytes read count even if there's an error, e.g. io.EOF
 		_ = r.bar.Add(n)
+		for _, h := range r.hashes {
+			h.Write(p[:n])
+		}
+	}
+	if err == io.EOF {
+		r.eof = true
 	}
 	return n, err
 }
 
 // Close closes the internal reader if it implements io.Closer and fills progress bar to full.
+//
+// With ReaderChecksum, it returns an error wrapping ErrChecksumMismatch
+// if the checksum is not as expected, or ErrNotFullyRead if not all
+// the data has been read, stopping progress bar and telling that
+// it has failed.
 func (r *Reader) Close() (err error) {
+	if err := r.verify(); err != nil {
+		_ = r.bar.Fail()
+		if closer, ok := r.r.(io.Closer); ok {
+			_ = closer.Close()
+		}
+		return err
+	}
 	if closer, ok := r.r.(io.Closer); ok {
 		if err := closer.Close(); err != nil {
 			return err
@@ -965,6 +1915,57 @@ func (r *Reader) Close() (err error) {
 	return r.bar.Finish()
 }
 
+// Writer is an io.Writer with a progress bar.
+type Writer struct {
+	w   io.Writer
+	bar *ProgressBar
+}
+
+// NewWriter creates a new Writer with given io.Writer and progress bar.
+func NewWriter(w io.Writer, bar *ProgressBar) Writer {
+	return Writer{
+		w:   w,
+		bar: bar,
+	}
+}
+
+// Write writes buffer p and adds the number of bytes written to the progress bar.
+//
+// With OptionRateLimit, it writes in bursts, waiting until
+// each of them is within the limit before writing it.
+func (w *Writer) Write(p []byte) (n int, err error) {
+	l := w.bar.config.limiter
+	for len(p) > 0 {
+		chunk := p
+		if l != nil {
+			chunk = p[:min(len(p), l.burst)]
+			l.wait(len(chunk))
+		}
+		m, err := w.w.Write(chunk)
+		if m > 0 {
+			_ = w.bar.Add(m)
+		}
+		n, p = n+m, p[m:]
+		if err != nil {
+			return n, err
+		}
+		if m < len(chunk) {
+			return n, io.ErrShortWrite
+		}
+	}
+	return n, nil
+}
+
+// Close closes the internal writer if it implements io.Closer and fills progress bar to full.
+func (w *Writer) Close() (err error) {
+	if closer, ok := w.w.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return w.bar.Finish()
+}
+
 // Write implements io.Writer, just in case.
 func (p *ProgressBar) Write(b []byte) (n int, err error) {
 	n = len(b)
@@ -982,6 +1983,46 @@ func (p *ProgressBar) Close() (err error) {
 	return p.Finish()
 }
 
+// status returns what is to be told about the progress, if anything.
+func status(c *config, s *state) string {
+	switch {
+	case s.failed:
+		return "failed"
+	case !s.pausedAt.IsZero():
+		return "paused"
+	case s.rolledBack && c.showRollback:
+		return "rolled back"
+	}
+	return ""
+}
+
+// currentRate returns the rate of progress per second to be displayed.
+func currentRate(c *config, s *state, now time.Time) float64 {
+	if !s.finished && !c.totalRate && len(s.counterLastTenRates) > 0 {
+		// display recent rolling average rate
+		return average(s.counterLastTenRates)
+	}
+	if t := now.Sub(s.startTime); t > 0 {
+		// if no average samples, or if finished, or total rate option is set
+		// then display total rate
+		return (s.currentBytes - float64(s.offset)) / t.Seconds()
+	}
+	return 0
+}
+
+// remainingTime returns the estimate of the time remaining at rate,
+// and whether there is one to be displayed.
+func remainingTime(c *config, s *state, rate float64) (time.Duration, bool) {
+	if c.max < s.currentNum || s.currentNum <= 0 {
+		return 0, false
+	}
+	var est time.Duration
+	if rate > 0 {
+		est = time.Duration(float64(c.max-s.currentNum) / rate * float64(time.Second))
+	}
+	return est, true
+}
+
 func average(xx []float64) float64 {
 	total := 0.0
 	for _, x := range xx {
@@ -993,28 +2034,26 @@ func average(xx []float64) float64 {
 var sizes = []string{"B", "KB", "MB", "GB", "TB", "PB", "EB"}
 
 func humanizeBytes(x float64) (string, string) {
+	b, suffix := appendHumanizedBytes(nil, x)
+	return string(b), suffix
+}
+
+func appendHumanizedBytes(b []byte, x float64) ([]byte, string) {
 	if x < 10 {
-		return fmt.Sprintf("%.0f", x), sizes[0]
+		return strconv.AppendFloat(b, x, 'f', 0, 64), sizes[0]
 	}
 	e := math.Floor(logn(x, 1000))
 	val, suffix := math.Floor(x/math.Pow(1000, e)*10+0.5)/10, sizes[int(e)]
 	if val < 10 {
-		return fmt.Sprintf("%.1f", val), suffix
+		return strconv.AppendFloat(b, val, 'f', 1, 64), suffix
 	}
-	return fmt.Sprintf("%.0f", val), suffix
+	return strconv.AppendFloat(b, val, 'f', 0, 64), suffix
 }
 
 func logn(n, b float64) float64 {
 	return math.Log(n) / math.Log(b)
 }
 
-func sp(s string, p bool) string {
-	if p {
-		return s
-	}
-	return ""
-}
-
 // termWidth function returns the visible width of the current terminal
 // and can be redefined for testing.
 var termWidth = func(w io.Writer) (width int, err error) {
@@ -1,9 +1,11 @@
 package progressbar
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/md5"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,6 +14,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -25,10 +28,15 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
-func OptionClock(clock func() time.Time) Option {
-	return func(p *ProgressBar) {
-		p.config.now = clock
-	}
+// clockFunc is a Clock telling the time returned by the function.
+type clockFunc func() time.Time
+
+func (f clockFunc) Now() time.Time {
+	return f()
+}
+
+func (f clockFunc) NewTicker(d time.Duration) Ticker {
+	return systemClock{}.NewTicker(d)
 }
 
 func BenchmarkRenderSimple(b *testing.B) {
@@ -50,7 +58,7 @@ func BenchmarkRenderTricky(b *testing.B) {
 func TestDefaults(t *testing.T) {
 	buf, clock := strings.Builder{}, time.Now()
 	bar := New(100,
-		OptionClock(func() time.Time { return clock }),
+		OptionClock(clockFunc(func() time.Time { return clock })),
 		OptionWriter(&buf))
 	clock = clock.Add(1 * time.Second)
 	bar.Add(10)
@@ -67,7 +75,7 @@ func TestZeroMax(t *testing.T) {
 		OptionShowElapsed(),
 		OptionShowIts(),
 		OptionShowCount(),
-		OptionClock(func() time.Time { return clock }),
+		OptionClock(clockFunc(func() time.Time { return clock })),
 		OptionWriter(&buf))
 	clock = clock.Add(1 * time.Second)
 	bar.Add(1)
@@ -83,7 +91,7 @@ func TestAddMax(t *testing.T) {
 		OptionShowElapsed(),
 		OptionShowIts(),
 		OptionShowCount(),
-		OptionClock(func() time.Time { return clock }),
+		OptionClock(clockFunc(func() time.Time { return clock })),
 		OptionWriter(&buf))
 	clock = clock.Add(1 * time.Second)
 	bar.Add(1)
@@ -103,7 +111,7 @@ func TestOptionShowCount(t *testing.T) {
 	bar := New(100,
 		OptionWidth(10),
 		OptionShowCount(),
-		OptionClock(func() time.Time { return clock }),
+		OptionClock(clockFunc(func() time.Time { return clock })),
 		OptionWriter(&buf))
 	clock = clock.Add(1 * time.Second)
 	bar.Add(10)
@@ -119,7 +127,7 @@ func TestOptionShowIts(t *testing.T) {
 	bar := New(100,
 		OptionWidth(10),
 		OptionShowIts(),
-		OptionClock(func() time.Time { return clock }),
+		OptionClock(clockFunc(func() time.Time { return clock })),
 		OptionWriter(&buf))
 	clock = clock.Add(1 * time.Second)
 	bar.Add(10)
@@ -135,7 +143,7 @@ func TestOptionShowBytes(t *testing.T) {
 	bar := New(100,
 		OptionWidth(10),
 		OptionShowBytes(),
-		OptionClock(func() time.Time { return clock }),
+		OptionClock(clockFunc(func() time.Time { return clock })),
 		OptionWriter(&buf))
 	clock = clock.Add(1 * time.Millisecond)
 	bar.Add(10)
@@ -152,7 +160,7 @@ func TestOptionShowCountWithIts(t *testing.T) {
 		OptionWidth(10),
 		OptionShowCount(),
 		OptionShowIts(),
-		OptionClock(func() time.Time { return clock }),
+		OptionClock(clockFunc(func() time.Time { return clock })),
 		OptionWriter(&buf))
 	clock = clock.Add(1 * time.Second)
 	bar.Add(10)
@@ -169,7 +177,7 @@ func TestOptionShowCountWithBytes(t *testing.T) {
 		OptionWidth(10),
 		OptionShowCount(),
 		OptionShowBytes(),
-		OptionClock(func() time.Time { return clock }),
+		OptionClock(clockFunc(func() time.Time { return clock })),
 		OptionWriter(&buf))
 	clock = clock.Add(1 * time.Second)
 	bar.Add(10)
@@ -188,7 +196,7 @@ func TestOptionTotalRate(t *testing.T) {
 		OptionShowBytes(),
 		OptionShowElapsed(),
 		OptionTotalRate(),
-		OptionClock(func() time.Time { return clock }),
+		OptionClock(clockFunc(func() time.Time { return clock })),
 		OptionWriter(&buf))
 	clock = clock.Add(1 * time.Second)
 	bar.Add(50000000)
@@ -210,7 +218,7 @@ func TestFinish(t *testing.T) {
 		OptionShowCount(),
 		OptionShowBytes(),
 		OptionShowIts(),
-		OptionClock(func() time.Time { return clock }),
+		OptionClock(clockFunc(func() time.Time { return clock })),
 		OptionWriter(&buf))
 	clock = clock.Add(1 * time.Millisecond)
 	bar.Finish()
@@ -227,7 +235,7 @@ func TestBarSlowAdd(t *testing.T) {
 		OptionWidth(10),
 		OptionShowIts(),
 		OptionShowRemaining(),
-		OptionClock(func() time.Time { return clock }),
+		OptionClock(clockFunc(func() time.Time { return clock })),
 		OptionWriter(&buf))
 	clock = clock.Add(3 * time.Second)
 	bar.Add(1)
@@ -242,7 +250,7 @@ func TestBarSmallBytes(t *testing.T) {
 		OptionShowBytes(),
 		OptionShowCount(),
 		OptionWidth(10),
-		OptionClock(func() time.Time { return clock }),
+		OptionClock(clockFunc(func() time.Time { return clock })),
 		OptionWriter(&buf))
 	for i := 1; i < 10; i++ {
 		clock = clock.Add(100 * time.Millisecond)
@@ -262,7 +270,7 @@ func TestBarFastBytes(t *testing.T) {
 		OptionShowBytes(),
 		OptionShowCount(),
 		OptionWidth(10),
-		OptionClock(func() time.Time { return clock }),
+		OptionClock(clockFunc(func() time.Time { return clock })),
 		OptionWriter(&buf))
 	clock = clock.Add(time.Millisecond)
 	bar.Add(2e7)
@@ -334,7 +342,7 @@ func TestOptionElapsed_spinner(t *testing.T) {
 		OptionShowElapsed(),
 		OptionShowIts(),
 		OptionShowCount(),
-		OptionClock(func() time.Time { return clock }),
+		OptionClock(clockFunc(func() time.Time { return clock })),
 		OptionWriter(&buf))
 	clock = clock.Add(1 * time.Second)
 	bar.Add(5)
@@ -352,17 +360,60 @@ func TestEstimated(t *testing.T) {
 }
 
 func TestSpinnerState(t *testing.T) {
-	clock := time.Now()
+	start := time.Now()
+	clock := start
 	bar := New(-1,
 		OptionWidth(100),
-		OptionClock(func() time.Time { return clock }))
+		OptionClock(clockFunc(func() time.Time { return clock })))
 	clock = clock.Add(1 * time.Second)
 	bar.Add(10)
 
 	assert.Equal(t, State{
+		Max:          -1,
+		Current:      10,
 		CurrentBytes: 10,
 		SecondsSince: 1,
+		Rate:         10,
+		StartTime:    start,
+	}, bar.State())
+}
+
+func TestStateAsRendered(t *testing.T) {
+	start := time.Now()
+	clock := start
+	bar := New(100,
+		OptionWidth(10),
+		OptionDescription("copying"),
+		OptionShowIts(),
+		OptionShowRemaining(),
+		OptionClock(clockFunc(func() time.Time { return clock })),
+		OptionWriter(io.Discard))
+	clock = clock.Add(1 * time.Second)
+	bar.Add(10)
+	clock = clock.Add(500 * time.Millisecond)
+	bar.Add(10)
+	assert.Equal(t, "copying  20% |██        | (15 it/s) [2s:5s] ", bar.String())
+	bar.Pause()
+
+	assert.Equal(t, State{
+		Max:            100,
+		Current:        20,
+		CurrentPercent: 0.2,
+		CurrentBytes:   20,
+		SecondsSince:   1.5,
+		SecondsLeft:    5, // [2s:5s]
+		Rate:           15,
+		Description:    "copying",
+		StartTime:      start,
+		Paused:         true,
 	}, bar.State())
+
+	bar.Finish()
+	s := bar.State()
+	assert.True(t, s.Finished)
+	assert.False(t, s.Paused)
+	assert.Equal(t, 100.0/1.5, s.Rate)
+	assert.Zero(t, s.SecondsLeft)
 }
 
 func TestReaderToBuffer(t *testing.T) {
@@ -670,7 +721,7 @@ func TestOptionFullWidth(t *testing.T) {
 			buf, clock := strings.Builder{}, time.Now()
 			bar := New(100, append(test.opts, []Option{
 				OptionFullWidth(),
-				OptionClock(func() time.Time { return clock }),
+				OptionClock(clockFunc(func() time.Time { return clock })),
 				OptionWriter(&buf),
 			}...)...)
 			clock = clock.Add(1 * time.Second)
@@ -757,7 +808,7 @@ func TestSpinners(t *testing.T) {
 			t.Parallel()
 			buf, clock := strings.Builder{}, time.Now()
 			spinner := New(-1, append(test.opts, []Option{
-				OptionClock(func() time.Time { return clock }),
+				OptionClock(clockFunc(func() time.Time { return clock })),
 				OptionWriter(&buf),
 			}...)...)
 			clock = clock.Add(950 * time.Millisecond)
@@ -775,3 +826,451 @@ func co(s string) string {
 	}
 	return regexp.MustCompile(`\r[ ]+\r`).ReplaceAllString(s, "\r")
 }
+
+func TestOptionHideCursor(t *testing.T) {
+	withTerminal(t)
+	buf := strings.Builder{}
+	bar := New(100, OptionWidth(10), OptionHideCursor(), OptionWriter(&buf))
+	bar.Add(10)
+	bar.Finish()
+	expect := "" +
+		"\033[?25l" +
+		"  0% |          | " +
+		"\r                  \r" +
+		" 10% |█         | " +
+		"\r                  \r" +
+		"100% |██████████| \n" +
+		"\033[?25h"
+	assert.Equal(t, co(expect), buf.String())
+	assert.Nil(t, bar.state.sigwatch)
+
+	buf.Reset()
+	bar = New(100, OptionWidth(10), OptionHideCursor(), OptionUseANSICodes(), OptionWriter(&buf))
+	bar.Clear()
+	assert.Equal(t, "\033[?25l\r  0% |          | \033[0K\033[2K\r\033[?25h", buf.String())
+
+	buf.Reset()
+	bar = New(100, OptionWidth(10), OptionHideCursor(), OptionVisible(false), OptionWriter(&buf))
+	bar.Stop()
+	assert.Equal(t, "", buf.String())
+	assert.Nil(t, bar.state.sigwatch)
+}
+
+func TestThrottledAdd(t *testing.T) {
+	buf, clock := strings.Builder{}, time.Now()
+	bar := New(10,
+		OptionWidth(10),
+		OptionThrottle(time.Second),
+		OptionClock(clockFunc(func() time.Time { return clock })),
+		OptionWriter(&buf))
+	clock = clock.Add(time.Second)
+	bar.Add(1) // renders, the bar is throttled from now on
+	bar.Add(2)
+	bar.Add(3)
+	assert.Equal(t, int64(5), bar.headroom-bar.room.Load()) // pending
+	assert.Equal(t, 6.0, bar.State().CurrentBytes)
+	assert.Equal(t, int64(0), bar.headroom-bar.room.Load())
+	assert.Equal(t, " 10% |█         | ", bar.String())
+
+	bar.Add(4) // fills the bar, so renders regardless of throttling
+	assert.Equal(t, "100% |██████████| ", bar.String())
+	assert.Error(t, bar.Add(1))
+}
+
+func TestThrottledRefresh(t *testing.T) {
+	bar := New(10, OptionWidth(10), OptionThrottle(20*time.Millisecond), OptionWriter(io.Discard))
+	bar.Add(1)
+	bar.Add(2)
+	assert.Eventually(t, func() bool {
+		return bar.String() == " 30% |███       | "
+	}, time.Second, time.Millisecond)
+}
+
+func TestConcurrencyThrottled(t *testing.T) {
+	bar := New(1000, OptionThrottle(time.Hour), OptionWriter(io.Discard))
+	var wg sync.WaitGroup
+	for range 999 {
+		wg.Add(1)
+		go func() {
+			bar.Add(1)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 999.0, bar.State().CurrentBytes)
+	assert.NoError(t, bar.Add(1))
+	assert.Equal(t, "100% |████████████████████████████████████████| ", bar.String())
+
+	bar = New(100, OptionThrottle(time.Hour), OptionWriter(io.Discard))
+	var added atomic.Int64
+	for range 200 {
+		wg.Add(1)
+		go func() {
+			if bar.Add(1) == nil {
+				added.Add(1)
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(100), added.Load()) // none past max
+	assert.Equal(t, 100.0, bar.State().CurrentBytes)
+}
+
+func TestThrottledSetMax(t *testing.T) {
+	bar := New(100, OptionThrottle(time.Hour), OptionWriter(io.Discard))
+	for range 51 {
+		bar.Add(1)
+	}
+	assert.ErrorIs(t, bar.SetMax(20), ErrExceedsMax) // pending increments are kept
+	assert.Equal(t, 51.0, bar.State().CurrentBytes)
+	assert.ErrorIs(t, bar.Add(1), ErrExceedsMax)
+
+	bar = New(100, OptionThrottle(time.Hour), OptionWriter(io.Discard))
+	for range 51 {
+		bar.Add(1)
+	}
+	assert.NoError(t, bar.SetMax(80))
+	assert.NoError(t, bar.Add(29))
+	assert.Equal(t, 80.0, bar.State().CurrentBytes)
+}
+
+func BenchmarkAddParallel(b *testing.B) {
+	bar := New64(1e18, OptionWriter(io.Discard), OptionShowIts(),
+		OptionThrottle(65*time.Millisecond))
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			bar.Add(1)
+		}
+	})
+}
+
+func BenchmarkAddParallelUnthrottled(b *testing.B) {
+	bar := New64(1e18, OptionWriter(io.Discard), OptionShowIts())
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			bar.Add(1)
+		}
+	})
+}
+
+type writesRecorder struct {
+	writes []string
+}
+
+func (w *writesRecorder) Write(b []byte) (int, error) {
+	w.writes = append(w.writes, string(b))
+	return len(b), nil
+}
+
+func TestSingleWritePerFrame(t *testing.T) {
+	withTerminal(t)
+	w, flushes := &writesRecorder{}, 0
+	bar := New(100,
+		OptionWidth(10),
+		OptionHideCursor(),
+		OptionFlush(func(io.Writer) error { flushes++; return nil }),
+		OptionWriter(w))
+	bar.Add(10)
+	bar.Stop()
+	assert.Equal(t, []string{
+		"\033[?25l  0% |          | ",
+		"\r                  \r 10% |█         | ",
+		"\r                  \r 10% |█         | ",
+		"\n\033[?25h",
+	}, w.writes)
+	assert.Equal(t, 4, flushes)
+}
+
+func TestDefaultFlush(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "progressbar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	assert.Nil(t, defaultFlush(f))
+	assert.Nil(t, defaultFlush(&strings.Builder{}))
+
+	buf := bytes.Buffer{}
+	bw := bufio.NewWriter(&buf)
+	bar := New(100, OptionWidth(10), OptionWriter(bw))
+	assert.Equal(t, "  0% |          | ", buf.String())
+	bar.Finish()
+	assert.True(t, strings.HasSuffix(buf.String(), "100% |██████████| \n"))
+}
+
+func TestAppendDuration(t *testing.T) {
+	for _, d := range []time.Duration{
+		0, 400 * time.Millisecond, 1500 * time.Millisecond, time.Minute,
+		61 * time.Second, time.Hour, 100*time.Hour + 59*time.Second, -3 * time.Second,
+	} {
+		assert.Equal(t, d.Round(time.Second).String(), string(appendDuration(nil, d)))
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		str    string
+		tricky bool
+		width  int
+	}{
+		{"plain", false, 5},
+		{"\033[1;31mred\033[0m \033[2K\r", false, 5},
+		{"\033(Bx\033", false, 5}, // not escape sequences
+		{"这是\033[32m测试\033[0m", true, 8},
+		{"👍🏽", true, 2},
+	}
+	for _, test := range tests {
+		c := config{trickyWidths: test.tricky}
+		assert.Equal(t, test.width, displayWidth(&c, []byte(test.str)), test.str)
+	}
+}
+
+func TestAppendPadded(t *testing.T) {
+	assert.Equal(t, "  5", string(appendPadded(nil, 5, 3)))
+	assert.Equal(t, "100", string(appendPadded(nil, 100, 3)))
+	assert.Equal(t, "1000", string(appendPadded(nil, 1000, 3)))
+	assert.Equal(t, " -5", string(appendPadded(nil, -5, 3)))
+}
+
+func TestOptionDiffUpdates(t *testing.T) {
+	buf := strings.Builder{}
+	bar := New(100, OptionWidth(10), OptionDiffUpdates(), OptionWriter(&buf))
+	bar.Add(10)
+	bar.Add(1)
+	bar.Add(0)
+	bar.Add(89)
+	expect := "" +
+		"\r  0% |          | \033[0K" +
+		"\r\033[1C1\033[4C█" +
+		"\r\033[2C1" +
+		"\r100\033[4C█████████"
+	assert.Equal(t, expect, buf.String())
+
+	buf.Reset()
+	bar.Clear()
+	bar.Set(100)
+	assert.Equal(t, "\033[2K\r\r100% |██████████| \033[0K", buf.String())
+}
+
+func TestOptionDiffUpdatesLayout(t *testing.T) {
+	buf, clock := strings.Builder{}, time.Now()
+	bar := New(100,
+		OptionWidth(10),
+		OptionShowIts(),
+		OptionDiffUpdates(),
+		OptionClock(clockFunc(func() time.Time { return clock })),
+		OptionWriter(&buf))
+	clock = clock.Add(time.Second)
+	bar.Add(10)
+	expect := "" +
+		"\r  0% |          | (0 it/s) \033[0K" +
+		"\r 10% |█         | (10 it/s) \033[0K"
+	assert.Equal(t, expect, buf.String())
+}
+
+func TestPauseResume(t *testing.T) {
+	buf, clock := strings.Builder{}, time.Now()
+	bar := New(10,
+		OptionWidth(10),
+		OptionShowElapsed(),
+		OptionShowIts(),
+		OptionClock(clockFunc(func() time.Time { return clock })),
+		OptionWriter(&buf))
+	clock = clock.Add(1 * time.Second)
+	bar.Add(1)
+	bar.Pause()
+	assert.Equal(t, " 10% |█         | (60 it/min) paused [1s] ", bar.String())
+
+	clock = clock.Add(1 * time.Minute)
+	bar.Add(1)
+	assert.Equal(t, " 20% |██        | (60 it/min) paused [1s] ", bar.String())
+	assert.Equal(t, 1.0, bar.State().SecondsSince)
+
+	bar.Resume()
+	clock = clock.Add(1 * time.Second)
+	bar.Add(2)
+	assert.Equal(t, " 40% |████      | (2 it/s) [2s] ", bar.String())
+	assert.Equal(t, 2.0, bar.State().SecondsSince)
+}
+
+func TestPauseFinish(t *testing.T) {
+	clock := time.Now()
+	bar := New(10,
+		OptionWidth(10),
+		OptionShowElapsed(),
+		OptionClock(clockFunc(func() time.Time { return clock })),
+		OptionWriter(io.Discard))
+	bar.Pause()
+	clock = clock.Add(1 * time.Minute)
+	bar.Finish()
+	assert.Equal(t, "100% |██████████| [0s] ", bar.String())
+	assert.True(t, bar.state.pausedAt.IsZero())
+}
+
+func TestOptionStartOffset(t *testing.T) {
+	clock := time.Now()
+	bar := New(100,
+		OptionWidth(10),
+		OptionShowCount(),
+		OptionShowIts(),
+		OptionShowRemaining(),
+		OptionStartOffset(50),
+		OptionClock(clockFunc(func() time.Time { return clock })),
+		OptionWriter(io.Discard))
+	assert.Equal(t, " 50% |█████     | (50/100, 0 it/s) [0s:0s] ", bar.String())
+
+	clock = clock.Add(1 * time.Second)
+	bar.Add(10)
+	assert.Equal(t, " 60% |██████    | (60/100, 10 it/s) [1s:4s] ", bar.String())
+	assert.Equal(t, 4.0, bar.State().SecondsLeft)
+}
+
+func TestOptionRestore(t *testing.T) {
+	clock := time.Now()
+	options := []Option{
+		OptionWidth(10),
+		OptionShowIts(),
+		OptionShowElapsed(),
+		OptionTotalRate(),
+		OptionClock(clockFunc(func() time.Time { return clock })),
+		OptionWriter(io.Discard),
+	}
+	bar := New(100, append(options, OptionStartOffset(10))...)
+	clock = clock.Add(10 * time.Second)
+	bar.Add(20)
+
+	data, err := json.Marshal(bar.State())
+	assert.Nil(t, err)
+	var s State
+	assert.Nil(t, json.Unmarshal(data, &s))
+	assert.True(t, s.StartTime.Equal(clock.Add(-10*time.Second)), s.StartTime)
+	s.StartTime = time.Time{}
+	assert.Equal(t, State{Max: 100, Current: 30, CurrentPercent: 0.3, CurrentBytes: 30,
+		SecondsSince: 10, SecondsLeft: 35, Rate: 2, OffsetBytes: 10}, s)
+
+	clock = clock.Add(1 * time.Hour)
+	bar = New(100, append(options, OptionRestore(s))...)
+	assert.Equal(t, " 30% |███       | (2 it/s) [10s] ", bar.String())
+	clock = clock.Add(10 * time.Second)
+	bar.Add(20)
+	assert.Equal(t, " 50% |█████     | (2 it/s) [20s] ", bar.String())
+
+	bar.Reset()
+	bar.Add(10)
+	assert.Equal(t, 10.0, bar.State().CurrentBytes)
+	assert.Zero(t, bar.State().OffsetBytes)
+}
+
+func TestRollback(t *testing.T) {
+	clock := time.Now()
+	bar := New(100,
+		OptionWidth(10),
+		OptionShowCount(),
+		OptionShowIts(),
+		OptionShowRollback(),
+		OptionClock(clockFunc(func() time.Time { return clock })),
+		OptionWriter(io.Discard))
+	clock = clock.Add(1 * time.Second)
+	bar.Add(30)
+	assert.Equal(t, " 30% |███       | (30/100, 30 it/s) ", bar.String())
+
+	clock = clock.Add(1 * time.Second)
+	bar.Add(20)
+	bar.Add(-10)
+	assert.Equal(t, " 40% |████      | (40/100, 25 it/s) rolled back ", bar.String())
+	assert.Equal(t, []float64{30, 20}, bar.state.counterLastTenRates)
+
+	bar.Set(-5)
+	assert.Equal(t, "  0% |          | (0/100, 25 it/s) rolled back ", bar.String())
+	assert.Equal(t, int64(100), bar.headroom)
+
+	clock = clock.Add(1 * time.Second)
+	bar.Add(10)
+	assert.Equal(t, " 10% |█         | (10/100, 25 it/s) ", bar.String())
+
+	clock = clock.Add(1 * time.Second)
+	bar.Add(50) // only 10 of 60 since the rollback are new
+	assert.Equal(t, " 60% |██████    | (60/100, 20 it/s) ", bar.String())
+}
+
+func TestSpinnerRollback(t *testing.T) {
+	bar := New(-1, OptionWriter(io.Discard))
+	bar.Add(3)
+	bar.Add(-5)
+	s := bar.State()
+	assert.Equal(t, int64(0), s.Current)
+	assert.Equal(t, 0.0, s.CurrentBytes)
+	bar.Add(len(spinners[9]) + 1)
+	assert.Equal(t, int64(1), bar.state.currentNum)
+}
+
+func TestOptionOverflow(t *testing.T) {
+	bar := New(10, OptionWidth(10), OptionShowCount(), OptionWriter(io.Discard))
+	bar.Add(8)
+	assert.ErrorIs(t, bar.Add(3), ErrExceedsMax)
+	assert.Equal(t, " 80% |████████  | (8/10) ", bar.String())
+	assert.Equal(t, 8.0, bar.State().CurrentBytes)
+	assert.NoError(t, bar.Add(2))
+
+	bar = New(10, OptionThrottle(time.Second), OptionWriter(io.Discard))
+	for range 4 {
+		bar.Add(1)
+	}
+	assert.ErrorIs(t, bar.Add(8), ErrExceedsMax)
+	assert.Equal(t, 4.0, bar.State().CurrentBytes) // pending increments are kept
+	assert.NoError(t, bar.Add(6))
+
+	bar = New(10, OptionWidth(10), OptionShowCount(), OptionOverflow(OverflowClamp), OptionWriter(io.Discard))
+	bar.Add(8)
+	assert.NoError(t, bar.Add(3))
+	assert.Equal(t, "100% |██████████| (10/10) ", bar.String())
+	assert.Equal(t, 10.0, bar.State().CurrentBytes)
+	bar.SetMax(5)
+	assert.Equal(t, "100% |██████████| (5/5) ", bar.String())
+
+	bar = New(10, OptionWidth(10), OptionShowCount(), OptionOverflow(OverflowGrow), OptionWriter(io.Discard))
+	bar.Add(8)
+	assert.NoError(t, bar.Add(12))
+	assert.Equal(t, "100% |██████████| (20/20) ", bar.String())
+	assert.Equal(t, int64(20), bar.Max64())
+
+	bar = New(10, OptionWidth(10), OptionShowCount(), OptionOverflow(OverflowUnknownLength), OptionWriter(io.Discard))
+	bar.Add(8)
+	assert.NoError(t, bar.Add(3))
+	s := bar.State()
+	assert.Equal(t, int64(-1), s.Max)
+	assert.Equal(t, int64(11), s.Current)
+	assert.True(t, strings.HasSuffix(bar.String(), " (11/?) "), bar.String())
+	assert.Equal(t, int64(1<<63-1), bar.headroom)
+}
+
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write(b []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestErrors(t *testing.T) {
+	_, err := NewE(-2)
+	assert.ErrorIs(t, err, ErrNegativeMax)
+	_, err = NewE(100, OptionSpinnerStyle(1), OptionWriter(io.Discard))
+	assert.ErrorIs(t, err, ErrInvalidSpinner)
+	assert.PanicsWithValue(t, ErrInvalidSpinner, func() { New(100, OptionSpinnerStyle(1)) })
+
+	bar, err := NewE(-1, OptionWriter(io.Discard))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, bar.SetMax(-1), ErrNegativeMax)
+
+	bar, _ = NewE(100, OptionWriter(io.Discard))
+	bar.Add(100)
+	assert.NoError(t, bar.Add(-10))
+	bar.Finish()
+	assert.ErrorIs(t, bar.Add(1), ErrFinished)
+	assert.ErrorIs(t, bar.Set(1), ErrFinished)
+
+	bar = New(100, OptionWriter(failingWriter{os.ErrClosed}))
+	err = bar.Add(1)
+	assert.ErrorIs(t, err, os.ErrClosed)
+	assert.Equal(t, "writing progress bar: file already closed", err.Error())
+}
@@ -0,0 +1,103 @@
+// Package progressbartest provides utilities for testing code using progress bars.
+package progressbartest
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/oerlikon/progressbar/v3"
+)
+
+// Clock is a fake progressbar.Clock, whose time only changes when it's told to.
+// Pass it to progress bars with progressbar.OptionClock.
+//
+// It is safe for concurrent use by multiple goroutines.
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*Ticker
+}
+
+// NewClock creates a new Clock telling time t.
+func NewClock(t time.Time) *Clock {
+	return &Clock{now: t}
+}
+
+// Now returns the time the clock tells.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// Advance moves the clock forward by d, delivering the ticks due by then.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		t.tick(c.now)
+	}
+}
+
+// NewTicker returns a new Ticker ticking with period d as the clock advances.
+func (c *Clock) NewTicker(d time.Duration) progressbar.Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &Ticker{c: make(chan time.Time, 1), clock: c, period: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Tickers returns the number of tickers running, e.g. to wait until the code
+// being tested waits for a tick before advancing the clock.
+func (c *Clock) Tickers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.tickers)
+}
+
+// Ticker is a progressbar.Ticker of Clock.
+type Ticker struct {
+	c      chan time.Time
+	clock  *Clock
+	period time.Duration
+	next   time.Time // guarded by clock's lock
+}
+
+// C returns the channel on which the ticks are delivered.
+func (t *Ticker) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop turns the ticker off.
+func (t *Ticker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	t.clock.tickers = slices.DeleteFunc(t.clock.tickers, func(u *Ticker) bool { return u == t })
+}
+
+// tick delivers a tick if it's due by now, dropping it if the previous one
+// hasn't been received yet, like time.Ticker does. it must be called with
+// the clock's lock acquired.
+func (t *Ticker) tick(now time.Time) {
+	if now.Before(t.next) {
+		return
+	}
+	select {
+	case t.c <- now:
+	default:
+	}
+	for !now.Before(t.next) {
+		t.next = t.next.Add(t.period)
+	}
+}
@@ -0,0 +1,90 @@
+package progressbartest
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oerlikon/progressbar/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+	ticker := clock.NewTicker(time.Second)
+	clock.Advance(500 * time.Millisecond)
+	assert.Equal(t, start.Add(500*time.Millisecond), clock.Now())
+	assert.Empty(t, ticker.C())
+
+	clock.Advance(3 * time.Second)
+	assert.Equal(t, start.Add(3500*time.Millisecond), <-ticker.C())
+	clock.Advance(500 * time.Millisecond)
+	assert.Equal(t, start.Add(4*time.Second), <-ticker.C())
+
+	assert.Equal(t, 1, clock.Tickers())
+	ticker.Stop()
+	assert.Equal(t, 0, clock.Tickers())
+	clock.Advance(time.Minute)
+	assert.Empty(t, ticker.C())
+}
+
+func TestClockProgressBar(t *testing.T) {
+	clock := NewClock(time.Now())
+	buf := strings.Builder{}
+	bar := progressbar.New(100,
+		progressbar.OptionWidth(10),
+		progressbar.OptionShowIts(),
+		progressbar.OptionShowElapsed(),
+		progressbar.OptionClock(clock),
+		progressbar.OptionWriter(&buf))
+	clock.Advance(2 * time.Second)
+	bar.Add(10)
+	assert.Equal(t, " 10% |█         | (5 it/s) [2s] ", bar.String())
+}
+
+func TestClockThrottle(t *testing.T) {
+	clock := NewClock(time.Now())
+	bar := progressbar.New(100,
+		progressbar.OptionWidth(10),
+		progressbar.OptionThrottle(time.Second),
+		progressbar.OptionClock(clock),
+		progressbar.OptionWriter(io.Discard))
+	bar.Add(10)
+	bar.Add(20)
+	assert.Equal(t, "  0% |          | ", bar.String())
+	for clock.Tickers() == 0 {
+		time.Sleep(time.Millisecond) // until the refresh is due
+	}
+	clock.Advance(time.Second)
+	assert.Eventually(t, func() bool {
+		return bar.String() == " 30% |███       | "
+	}, time.Second, time.Millisecond)
+}
+
+func TestClockRateLimit(t *testing.T) {
+	clock := NewClock(time.Now())
+	bar := progressbar.New(300,
+		progressbar.OptionRateLimit(100, 100),
+		progressbar.OptionClock(clock),
+		progressbar.OptionWriter(io.Discard))
+	r := progressbar.NewReader(strings.NewReader(strings.Repeat("x", 300)), bar)
+
+	done := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, &r)
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			assert.Equal(t, 300.0, bar.State().CurrentBytes)
+			return
+		case <-time.After(time.Millisecond):
+			if clock.Tickers() > 0 { // the reader waits
+				clock.Advance(time.Second)
+			}
+		}
+	}
+}
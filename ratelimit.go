@@ -0,0 +1,59 @@
+package progressbar
+
+import (
+	"sync"
+	"time"
+)
+
+// OptionRateLimit limits the rate at which Reader and Writer of progress bar
+// transfer data to bytesPerSecond on average since the bar is created, in bursts
+// of up to burst bytes, or a tenth of a second's worth if burst is not positive.
+// The limit is shown next to the rate with OptionShowBytes.
+func OptionRateLimit(bytesPerSecond int64, burst int) Option {
+	return func(p *ProgressBar) {
+		p.config.limiter = nil
+		if bytesPerSecond > 0 {
+			p.config.limiter = newLimiter(bytesPerSecond, burst)
+		}
+	}
+}
+
+// limiter is a token bucket limiting the rate of transfer.
+type limiter struct {
+	rate  float64 // bytes per second
+	burst int
+	clock Clock
+
+	mu     sync.Mutex
+	tokens float64 // may be negative if waiting
+	last   time.Time
+}
+
+func newLimiter(rate int64, burst int) *limiter {
+	if burst <= 0 {
+		burst = int(max(rate/10, 1))
+	}
+	return &limiter{rate: float64(rate), burst: burst} // starts empty
+}
+
+// start starts filling the bucket, telling the time by clock.
+func (l *limiter) start(clock Clock) {
+	l.clock, l.last = clock, clock.Now()
+}
+
+// wait waits until n bytes can be transferred. n must not exceed the burst.
+func (l *limiter) wait(n int) {
+	l.mu.Lock()
+	now := l.clock.Now()
+	l.tokens = min(l.tokens+l.rate*now.Sub(l.last).Seconds(), float64(l.burst))
+	l.last = now
+	l.tokens -= float64(n)
+	d := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if d > 0 {
+		t := l.clock.NewTicker(d)
+		<-t.C()
+		t.Stop()
+	}
+}
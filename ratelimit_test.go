@@ -0,0 +1,48 @@
+package progressbar
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionRateLimit(t *testing.T) {
+	bar := New(300, OptionWidth(10), OptionShowBytes(), OptionRateLimit(2000, 100), OptionWriter(io.Discard))
+	r := NewReader(strings.NewReader(strings.Repeat("x", 300)), bar)
+	start := time.Now()
+	b, err := io.ReadAll(&r)
+	assert.NoError(t, err)
+	assert.Len(t, b, 300)
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	assert.Equal(t, 300.0, bar.State().CurrentBytes)
+	assert.True(t, strings.HasSuffix(bar.String(), ", limit 2.0 KB/s) "), bar.String())
+
+	buf := bytes.Buffer{}
+	bar = New(300, OptionWidth(10), OptionRateLimit(2000, 100), OptionWriter(io.Discard))
+	w := NewWriter(&buf, bar)
+	start = time.Now()
+	n, err := w.Write(b)
+	assert.NoError(t, err)
+	assert.Equal(t, 300, n)
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	assert.Equal(t, 300, buf.Len())
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "100% |██████████| ", bar.String())
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(1000, 0)
+	assert.Equal(t, 100, l.burst)
+	start := time.Now()
+	l.start(systemClock{})
+	l.wait(100) // the bucket starts empty
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
+	start = time.Now()
+	l.wait(100) // within the burst
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+}
@@ -0,0 +1,84 @@
+package progressbar
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// interruptSignals are the signals upon which the terminal
+// is expected to be returned to the user.
+var interruptSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// sigwatch relays interrupt signals to a progress bar while it's active.
+type sigwatch struct {
+	ch   chan os.Signal
+	done chan struct{}
+}
+
+// watchSignals starts handling interrupt signals. this function is not
+// thread-safe, so it must be called with an acquired lock.
+func (p *ProgressBar) watchSignals() {
+	if p.state.sigwatch != nil {
+		return
+	}
+	w := &sigwatch{ch: make(chan os.Signal, 1), done: make(chan struct{})}
+	signal.Notify(w.ch, interruptSignals...)
+	p.state.sigwatch = w
+
+	go func() {
+		select {
+		case sig := <-w.ch:
+			p.interrupt(w, sig)
+		case <-w.done:
+		}
+	}()
+}
+
+// unwatchSignals stops handling interrupt signals. this function is not
+// thread-safe, so it must be called with an acquired lock.
+func (p *ProgressBar) unwatchSignals() {
+	if w := p.state.sigwatch; w != nil {
+		signal.Stop(w.ch)
+		close(w.done)
+		p.state.sigwatch = nil
+	}
+}
+
+// interrupt restores the terminal once the process has received an interrupt
+// signal, and then either calls the user's handler or raises the signal again.
+func (p *ProgressBar) interrupt(w *sigwatch, sig os.Signal) {
+	p.Lock()
+	handle := p.config.handleInterrupt && !p.state.finished
+	if p.state.sigwatch == w {
+		if handle {
+			_ = p.stop()
+		}
+		p.unwatchSignals()
+		p.showCursor()
+		_ = flushFrame(&p.config, &p.state)
+	}
+	onInterrupt := p.config.onInterrupt
+	p.Unlock()
+
+	signal.Stop(w.ch)
+	if handle && onInterrupt != nil {
+		onInterrupt(sig)
+		return
+	}
+	raiseSignal(sig)
+}
+
+// raiseSignal function delivers sig to the current process
+// and can be redefined for testing.
+var raiseSignal = func(sig os.Signal) {
+	if p, err := os.FindProcess(os.Getpid()); err == nil && p.Signal(sig) == nil {
+		return
+	}
+	// signals can't always be sent, e.g. on windows, so exit with
+	// the conventional code of a process terminated by the signal
+	if s, ok := sig.(syscall.Signal); ok {
+		os.Exit(128 + int(s))
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,104 @@
+package progressbar
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterruptShowsCursor(t *testing.T) {
+	raised := make(chan os.Signal, 1)
+	saved := raiseSignal
+	raiseSignal = func(sig os.Signal) { raised <- sig }
+	defer func() { raiseSignal = saved }()
+
+	buf := strings.Builder{}
+	bar := New(100, OptionWidth(10), OptionHideCursor(), OptionUseANSICodes(), OptionWriter(&buf))
+	bar.Add(10)
+
+	w := bar.state.sigwatch
+	if !assert.NotNil(t, w) {
+		return
+	}
+	w.ch <- os.Interrupt
+	assert.Equal(t, os.Interrupt, <-raised)
+
+	bar.Lock()
+	defer bar.Unlock()
+	assert.Nil(t, bar.state.sigwatch)
+	assert.False(t, bar.state.cursorHidden)
+	assert.True(t, strings.HasSuffix(buf.String(), "\033[?25h"), buf.String())
+}
+
+func TestOptionHandleInterrupt(t *testing.T) {
+	saved := raiseSignal
+	raiseSignal = func(sig os.Signal) { t.Error("signal raised", sig) }
+	defer func() { raiseSignal = saved }()
+
+	handled := make(chan os.Signal, 1)
+	buf := strings.Builder{}
+	bar := New(100,
+		OptionWidth(10),
+		OptionHideCursor(),
+		OptionUseANSICodes(),
+		OptionHandleInterrupt(func(sig os.Signal) { handled <- sig }),
+		OptionWriter(&buf))
+	bar.Add(10)
+	buf.Reset()
+
+	bar.state.sigwatch.ch <- os.Interrupt
+	assert.Equal(t, os.Interrupt, <-handled)
+
+	bar.Lock()
+	defer bar.Unlock()
+	assert.Equal(t, "\r 10% |█         | \033[0K\n\033[?25h", buf.String())
+	assert.Nil(t, bar.state.sigwatch)
+	assert.True(t, bar.state.closed)
+}
+
+func TestOptionHandleInterruptFinish(t *testing.T) {
+	bar := New(100, OptionHandleInterrupt(nil), OptionWriter(io.Discard))
+	assert.NotNil(t, bar.state.sigwatch)
+	bar.Clear()
+	assert.NotNil(t, bar.state.sigwatch)
+	bar.Finish()
+	assert.Nil(t, bar.state.sigwatch)
+}
+
+func TestOptionHandleInterruptMax(t *testing.T) {
+	bar := New(10, OptionHandleInterrupt(nil), OptionWriter(io.Discard))
+	assert.NotNil(t, bar.state.sigwatch)
+	bar.Add(10)
+	assert.Nil(t, bar.state.sigwatch)
+	bar.Reset()
+	bar.Add(5)
+	assert.NotNil(t, bar.state.sigwatch)
+	bar.Finish()
+	assert.Nil(t, bar.state.sigwatch)
+
+	// while the cursor is hidden, the signal is only watched to show it
+	raised := make(chan os.Signal, 1)
+	saved := raiseSignal
+	raiseSignal = func(sig os.Signal) { raised <- sig }
+	defer func() { raiseSignal = saved }()
+
+	buf := strings.Builder{}
+	bar = New(10,
+		OptionWidth(10),
+		OptionHideCursor(),
+		OptionUseANSICodes(),
+		OptionHandleInterrupt(func(sig os.Signal) { t.Error("signal handled", sig) }),
+		OptionWriter(&buf))
+	bar.Add(10)
+	buf.Reset()
+	bar.state.sigwatch.ch <- os.Interrupt
+	assert.Equal(t, os.Interrupt, <-raised)
+
+	bar.Lock()
+	defer bar.Unlock()
+	assert.Equal(t, "\033[?25h", buf.String())
+	assert.Nil(t, bar.state.sigwatch)
+}
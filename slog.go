@@ -0,0 +1,103 @@
+package progressbar
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+)
+
+// SlogHandlerOptions are options for a SlogHandler.
+type SlogHandlerOptions struct {
+	// Handler constructs the underlying handler that formats records into w.
+	// If nil, slog.NewTextHandler with default options is used.
+	Handler func(w io.Writer) slog.Handler
+
+	// AddProgress makes the handler attach progress bar's current state
+	// to each record as a "progress" group of attributes.
+	AddProgress bool
+}
+
+// SlogHandler is a slog.Handler that routes log records around an active progress bar.
+//
+// Each record is formatted into a buffer while the bar is locked, and then printed
+// above the bar, which is rendered anew right below it. Once the bar is finished or
+// stopped, records are written to bar's writer as is.
+type SlogHandler struct {
+	h   slog.Handler
+	buf *bytes.Buffer
+	bar *ProgressBar
+
+	addProgress bool
+}
+
+// NewSlogHandler creates a new SlogHandler that prints records above the progress bar.
+// If opts is nil, the default options are used.
+func NewSlogHandler(bar *ProgressBar, opts *SlogHandlerOptions) *SlogHandler {
+	if opts == nil {
+		opts = &SlogHandlerOptions{}
+	}
+	buf := new(bytes.Buffer)
+	newHandler := opts.Handler
+	if newHandler == nil {
+		newHandler = func(w io.Writer) slog.Handler {
+			return slog.NewTextHandler(w, nil)
+		}
+	}
+	return &SlogHandler{
+		h:           newHandler(buf),
+		buf:         buf,
+		bar:         bar,
+		addProgress: opts.AddProgress,
+	}
+}
+
+// Enabled reports whether the underlying handler handles records at the given level.
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.h.Enabled(ctx, level)
+}
+
+// Handle formats the record with the underlying handler and prints it above the progress bar.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	p := h.bar
+	p.Lock()
+	defer p.Unlock()
+
+	if h.addProgress {
+		r = r.Clone()
+		r.AddAttrs(p.progressAttr())
+	}
+
+	h.buf.Reset()
+	if err := h.h.Handle(ctx, r); err != nil {
+		return err
+	}
+	return p.printAbove(h.buf.Bytes())
+}
+
+// WithAttrs returns a new SlogHandler whose underlying handler has the given attributes.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.h = h.h.WithAttrs(attrs)
+	return &h2
+}
+
+// WithGroup returns a new SlogHandler whose underlying handler has the given group.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.h = h.h.WithGroup(name)
+	return &h2
+}
+
+// progressAttr returns progress bar's current state as a group of log attributes.
+// this function is not thread-safe, so it must be called with an acquired lock.
+func (p *ProgressBar) progressAttr() slog.Attr {
+	attrs := make([]any, 0, 2)
+	if !p.config.ignoreLength {
+		attrs = append(attrs, slog.Int("percent", p.state.currentPercent))
+	}
+	if p.config.description != "" {
+		attrs = append(attrs, slog.String("description", p.config.description))
+	}
+	return slog.Group("progress", attrs...)
+}
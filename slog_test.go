@@ -0,0 +1,74 @@
+package progressbar
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSlogHandler(bar *ProgressBar, addProgress bool) *SlogHandler {
+	return NewSlogHandler(bar, &SlogHandlerOptions{
+		Handler: func(w io.Writer) slog.Handler {
+			return slog.NewTextHandler(w, &slog.HandlerOptions{
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if a.Key == slog.TimeKey && len(groups) == 0 {
+						return slog.Attr{}
+					}
+					return a
+				},
+			})
+		},
+		AddProgress: addProgress,
+	})
+}
+
+func TestSlogHandler(t *testing.T) {
+	buf, clock := strings.Builder{}, time.Now()
+	bar := New(100,
+		OptionWidth(10),
+		OptionClock(clockFunc(func() time.Time { return clock })),
+		OptionWriter(&buf))
+	logger := slog.New(newTestSlogHandler(bar, false))
+	bar.Add(10)
+	logger.Info("hello", "n", 1)
+	expect := "" +
+		"  0% |          | " +
+		"\r                  \r" +
+		" 10% |█         | " +
+		"\r                  \r" +
+		"level=INFO msg=hello n=1\n" +
+		" 10% |█         | "
+	assert.Equal(t, co(expect), buf.String())
+}
+
+func TestSlogHandlerAddProgress(t *testing.T) {
+	buf := strings.Builder{}
+	bar := New(100,
+		OptionWidth(10),
+		OptionDescription("copying"),
+		OptionUseANSICodes(),
+		OptionWriter(&buf))
+	logger := slog.New(newTestSlogHandler(bar, true)).With("worker", 3)
+	bar.Add(25)
+	buf.Reset()
+	logger.Warn("slow")
+	expect := "" +
+		"\033[2K\r" +
+		"level=WARN msg=slow worker=3 progress.percent=25 progress.description=copying\n" +
+		"\rcopying  25% |██        | \033[0K"
+	assert.Equal(t, expect, buf.String())
+}
+
+func TestSlogHandlerFinished(t *testing.T) {
+	buf := strings.Builder{}
+	bar := New(100, OptionWidth(10), OptionWriter(&buf))
+	logger := slog.New(newTestSlogHandler(bar, false))
+	bar.Finish()
+	buf.Reset()
+	logger.Info("done")
+	assert.Equal(t, "level=INFO msg=done\n", buf.String())
+}
@@ -0,0 +1,210 @@
+package progressbar
+
+import (
+	"io"
+	"os"
+	"strconv"
+
+	"golang.org/x/term"
+)
+
+// Color is a terminal color: either one of the 16 basic colors,
+// a color from the 256-color palette or a 24-bit RGB color.
+//
+// The zero value is no color, meaning the terminal's default.
+type Color struct {
+	kind    colorKind
+	r, g, b uint8 // index of basic and palette colors is kept in r
+}
+
+type colorKind uint8
+
+const (
+	colorNone colorKind = iota
+	colorBasic
+	colorPalette
+	colorRGB
+)
+
+// Basic terminal colors, their look depends on terminal's settings.
+var (
+	Black   = BasicColor(0)
+	Red     = BasicColor(1)
+	Green   = BasicColor(2)
+	Yellow  = BasicColor(3)
+	Blue    = BasicColor(4)
+	Magenta = BasicColor(5)
+	Cyan    = BasicColor(6)
+	White   = BasicColor(7)
+)
+
+// BasicColor returns one of the 16 basic terminal colors,
+// with 0-7 being normal and 8-15 being bright colors.
+func BasicColor(n uint8) Color {
+	return Color{kind: colorBasic, r: n & 15}
+}
+
+// PaletteColor returns a color from the 256-color palette.
+func PaletteColor(n uint8) Color {
+	return Color{kind: colorPalette, r: n}
+}
+
+// RGB returns a 24-bit "truecolor" color.
+func RGB(r, g, b uint8) Color {
+	return Color{kind: colorRGB, r: r, g: g, b: b}
+}
+
+// appendSGR appends color's SGR parameters to b, base is 30 for foreground
+// and 40 for background colors.
+func (c Color) appendSGR(b []byte, base int) []byte {
+	switch c.kind {
+	case colorBasic:
+		if c.r < 8 {
+			return strconv.AppendInt(b, int64(base+int(c.r)), 10)
+		}
+		return strconv.AppendInt(b, int64(base+60+int(c.r)-8), 10)
+	case colorPalette:
+		b = strconv.AppendInt(b, int64(base+8), 10)
+		b = append(b, ";5;"...)
+		return strconv.AppendInt(b, int64(c.r), 10)
+	case colorRGB:
+		b = strconv.AppendInt(b, int64(base+8), 10)
+		b = append(b, ";2;"...)
+		b = strconv.AppendInt(b, int64(c.r), 10)
+		b = append(b, ';')
+		b = strconv.AppendInt(b, int64(c.g), 10)
+		b = append(b, ';')
+		return strconv.AppendInt(b, int64(c.b), 10)
+	}
+	return b
+}
+
+// Style defines how a progress bar element looks.
+//
+// The zero value is no styling at all.
+type Style struct {
+	Fg, Bg    Color
+	Bold      bool
+	Faint     bool
+	Italic    bool
+	Underline bool
+}
+
+// appendSGR appends the ANSI escape sequence setting up the style to b.
+func (st Style) appendSGR(b []byte) []byte {
+	start := len(b)
+	b = append(b, '\033') // each parameter is prepended with a separator
+	if st.Bold {
+		b = append(b, ";1"...)
+	}
+	if st.Faint {
+		b = append(b, ";2"...)
+	}
+	if st.Italic {
+		b = append(b, ";3"...)
+	}
+	if st.Underline {
+		b = append(b, ";4"...)
+	}
+	if st.Fg.kind != colorNone {
+		b = st.Fg.appendSGR(append(b, ';'), 30)
+	}
+	if st.Bg.kind != colorNone {
+		b = st.Bg.appendSGR(append(b, ';'), 40)
+	}
+	if len(b) == start+1 {
+		return b[:start] // nothing to set up
+	}
+	b[start+1] = '[' // the first separator turns into the control sequence introducer
+	return append(b, 'm')
+}
+
+// Styles defines how each of the progress bar elements looks.
+type Styles struct {
+	Saucer        Style
+	SaucerHead    Style
+	SaucerPadding Style
+	BarStart      Style
+	BarEnd        Style
+	Percent       Style
+	Description   Style
+	Rate          Style // applies to the whole parenthesized count and rate section
+
+	// Gradient, if set, overrides foreground color of the saucer (and the saucer head
+	// unless it has one of its own) with a color shifting across the given colors
+	// as the progress goes from 0% to 100%. RGB colors are interpolated smoothly,
+	// any other colors are switched in steps.
+	Gradient []Color
+}
+
+// OptionStyles sets styles of progress bar's elements.
+//
+// Unlike OptionUseColorCodes it doesn't need any markup in theme or description strings.
+// Styling is disabled automatically if the NO_COLOR environment variable is set,
+// TERM is "dumb" or the writer is not a terminal, unless FORCE_COLOR is set.
+func OptionStyles(styles Styles) Option {
+	return func(p *ProgressBar) {
+		p.config.styles = styles
+		p.config.stylesSet = true
+	}
+}
+
+// sgrReset is the ANSI escape sequence resetting all styles.
+const sgrReset = "\033[0m"
+
+// sgr returns the ANSI escape sequence setting up style st, if there's anything to set up.
+func sgr(st Style) string {
+	return string(st.appendSGR(nil))
+}
+
+// paint wraps s into ANSI escape sequences setting up and resetting style st.
+func paint(st Style, s string) string {
+	if s == "" || st == (Style{}) {
+		return s
+	}
+	return sgr(st) + s + sgrReset
+}
+
+// gradient returns the color of the gradient at given percent.
+func gradient(colors []Color, percent int) Color {
+	if len(colors) == 1 {
+		return colors[0]
+	}
+	percent = min(max(percent, 0), 100)
+	pos := float64(percent) / 100 * float64(len(colors)-1)
+	i := int(pos)
+	if i == len(colors)-1 {
+		return colors[i]
+	}
+	c0, c1, f := colors[i], colors[i+1], pos-float64(i)
+	if c0.kind != colorRGB || c1.kind != colorRGB {
+		if f < 0.5 {
+			return c0
+		}
+		return c1
+	}
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a) + (float64(b)-float64(a))*f + 0.5)
+	}
+	return RGB(mix(c0.r, c1.r), mix(c0.g, c1.g), mix(c0.b, c1.b))
+}
+
+// colorsEnabled reports whether colors should be written to w.
+func colorsEnabled(w io.Writer) bool {
+	switch {
+	case envFlag("FORCE_COLOR"):
+		return true
+	case os.Getenv("NO_COLOR") != "", os.Getenv("TERM") == "dumb":
+		return false
+	}
+	return isTerminal(w)
+}
+
+// isTerminal function reports whether w is a terminal
+// and can be redefined for testing.
+var isTerminal = func(w io.Writer) bool {
+	if f, ok := w.(*os.File); ok {
+		return term.IsTerminal(int(f.Fd()))
+	}
+	return false
+}
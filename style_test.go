@@ -0,0 +1,92 @@
+package progressbar
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withTerminal(t *testing.T) {
+	saved := isTerminal
+	isTerminal = func(w io.Writer) bool { return true }
+	t.Cleanup(func() { isTerminal = saved })
+}
+
+func TestStyleSGR(t *testing.T) {
+	tests := []struct {
+		style    Style
+		expected string
+	}{
+		{Style{Bold: true}, "\033[1m"},
+		{Style{Fg: Red}, "\033[31m"},
+		{Style{Fg: BasicColor(9), Bg: Blue}, "\033[91;44m"},
+		{Style{Fg: PaletteColor(208), Underline: true}, "\033[4;38;5;208m"},
+		{Style{Fg: RGB(1, 2, 3), Bg: RGB(4, 5, 6), Faint: true, Italic: true}, "\033[2;3;38;2;1;2;3;48;2;4;5;6m"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, sgr(test.style))
+	}
+}
+
+func TestGradient(t *testing.T) {
+	colors := []Color{RGB(0, 0, 0), RGB(200, 100, 0), RGB(200, 200, 200)}
+	assert.Equal(t, RGB(0, 0, 0), gradient(colors, 0))
+	assert.Equal(t, RGB(100, 50, 0), gradient(colors, 25))
+	assert.Equal(t, RGB(200, 100, 0), gradient(colors, 50))
+	assert.Equal(t, RGB(200, 200, 200), gradient(colors, 100))
+	assert.Equal(t, Green, gradient([]Color{Red, Green}, 60))
+	assert.Equal(t, Red, gradient([]Color{Red}, 60))
+}
+
+func TestOptionStyles(t *testing.T) {
+	clearColorEnv(t)
+	withTerminal(t)
+	buf := strings.Builder{}
+	bar := New(100,
+		OptionWidth(4),
+		OptionDescription("go"),
+		OptionShowCount(),
+		OptionStyles(Styles{
+			Saucer:      Style{Fg: Green},
+			BarStart:    Style{Faint: true},
+			BarEnd:      Style{Faint: true},
+			Percent:     Style{Bold: true},
+			Description: Style{Fg: Cyan},
+			Rate:        Style{Fg: Yellow},
+		}),
+		OptionWriter(&buf))
+	bar.Add(50)
+	expect := "" +
+		"\033[36mgo\033[0m \033[1m 50%\033[0m " +
+		"\033[2m|\033[0m\033[32m██\033[0m  \033[2m|\033[0m " +
+		"\033[33m(50/100)\033[0m "
+	assert.Equal(t, expect, bar.String())
+	assert.Equal(t, 24, bar.state.maxLineWidth)
+}
+
+func TestOptionStylesGradient(t *testing.T) {
+	clearColorEnv(t)
+	withTerminal(t)
+	bar := New(100,
+		OptionWidth(2),
+		OptionTheme(Theme{Saucer: "=", SaucerHead: ">", SaucerPadding: " ", BarStart: "[", BarEnd: "]"}),
+		OptionStyles(Styles{Gradient: []Color{RGB(0, 0, 0), RGB(100, 100, 100)}}),
+		OptionWriter(io.Discard))
+	bar.Add(50)
+	assert.Equal(t, " 50% [\033[38;2;50;50;50m>\033[0m ] ", bar.String())
+}
+
+func TestOptionStylesDisabled(t *testing.T) {
+	clearColorEnv(t)
+	styles := OptionStyles(Styles{Percent: Style{Bold: true}})
+
+	bar := New(100, OptionWidth(2), styles, OptionWriter(io.Discard))
+	assert.Equal(t, "  0% |  | ", bar.String())
+
+	withTerminal(t)
+	t.Setenv("NO_COLOR", "1")
+	bar = New(100, OptionWidth(2), styles, OptionWriter(io.Discard))
+	assert.Equal(t, "  0% |  | ", bar.String())
+}